@@ -0,0 +1,387 @@
+/**
+ * @file agent.go
+ * @author Mikhail Klementyev jollheef<AT>riseup.net
+ * @license GNU AGPLv3
+ * @date October, 2026
+ * @brief remote checker agents
+ *
+ * Provide http task server for checker agents and agent itself. If agents
+ * are enabled, checkers are not run by tin_foil_hat, but queued for agents,
+ * which pull tasks, run checkers locally and report results back.
+ */
+
+package checker
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jollheef/tin_foil_hat/steward"
+)
+
+const (
+	agentTokenHeader    = "X-Agent-Token"
+	agentPollTimeout    = 30 * time.Second // long polling of tasks
+	maxAgentResultSize  = 1 << 20
+	defaultAgentWorkers = 16
+)
+
+// wait time for result after deadline, agent kills checker by the same
+// deadline and needs time to report it
+var agentGrace = 5 * time.Second
+
+// task for checker agent, timeout is set when task is sent to agent
+type agentTask struct {
+	ID       uint64          `json:"id"`
+	Team     steward.Team    `json:"team"`
+	Service  steward.Service `json:"service"`
+	Action   string          `json:"action"`
+	Arg      string          `json:"arg"` // flag for put, cred for get
+	Place    int             `json:"place"`
+	Timeout  time.Duration   `json:"timeout"`
+	deadline time.Time
+}
+
+// result of task reported by checker agent
+type agentResult struct {
+	ID       uint64               `json:"id"`
+	Action   string               `json:"action"`
+	State    steward.ServiceState `json:"state"`
+	Ret      int                  `json:"ret"`
+	Duration time.Duration        `json:"duration"`
+	Cred     string               `json:"cred"`
+	Flag     string               `json:"flag"`
+	FlagID   string               `json:"flag_id"`
+	Public   string               `json:"public"`
+	Logs     string               `json:"logs"`
+}
+
+func newAgentResult(id uint64, res result) agentResult {
+	return agentResult{ID: id, Action: res.action, State: res.state,
+		Ret: res.ret, Duration: res.duration, Cred: res.cred,
+		Flag: res.flag, FlagID: res.flagID, Public: res.public,
+		Logs: res.logs}
+}
+
+func (r agentResult) result() result {
+	return result{action: r.Action, state: r.State, ret: r.Ret,
+		duration: r.Duration, cred: r.Cred, flag: r.Flag,
+		flagID: r.FlagID, public: r.Public, logs: r.Logs}
+}
+
+var agents = struct {
+	sync.Mutex
+	enabled bool
+	nextID  uint64
+	tasks   chan agentTask
+	pending map[uint64]chan result // { task id : result of task }
+}{
+	tasks:   make(chan agentTask),
+	pending: make(map[uint64]chan result),
+}
+
+func agentsEnabled() bool {
+
+	agents.Lock()
+	defer agents.Unlock()
+
+	return agents.enabled
+}
+
+// callAgent queue action for checker agents and wait for result, task not
+// taken or lost by agents is checker error
+func callAgent(ctx context.Context, team steward.Team, svc steward.Service,
+	action, arg string, place int) (res result, err error) {
+
+	res.action = action
+
+	done := make(chan result, 1)
+
+	agents.Lock()
+	agents.nextID++
+	id := agents.nextID
+	agents.pending[id] = done
+	agents.Unlock()
+
+	defer func() {
+		agents.Lock()
+		delete(agents.pending, id)
+		agents.Unlock()
+	}()
+
+	// Agents do not need api token of team
+	team.Token = ""
+
+	deadline, _ := ctx.Deadline()
+
+	task := agentTask{ID: id, Team: team, Service: svc, Action: action,
+		Arg: arg, Place: place, deadline: deadline}
+
+	select {
+	case agents.tasks <- task:
+	case <-ctx.Done():
+		if ctx.Err() == context.Canceled {
+			err = timedOut(ctx, &res)
+			return
+		}
+		res.state = steward.StatusError
+		res.logs = "no free checker agent"
+		return
+	}
+
+	select {
+	case res = <-done:
+		return
+	case <-ctx.Done():
+	}
+
+	if ctx.Err() == context.Canceled {
+		err = timedOut(ctx, &res)
+		return
+	}
+
+	select {
+	case res = <-done:
+		return
+	case <-time.After(agentGrace):
+	}
+
+	res.state = steward.StatusError
+	res.logs = "result of checker agent is lost"
+	return
+}
+
+func agentAuth(token string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", "POST")
+			http.Error(w, "Method not allowed",
+				http.StatusMethodNotAllowed)
+			return
+		}
+
+		if subtle.ConstantTimeCompare(
+			[]byte(r.Header.Get(agentTokenHeader)),
+			[]byte(token)) != 1 {
+
+			log.Println("\tInvalid agent token from", r.RemoteAddr)
+			http.Error(w, "Invalid token", http.StatusUnauthorized)
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// serveTask send task to agent or no content if there is no task for
+// poll timeout
+func serveTask(w http.ResponseWriter, r *http.Request) {
+
+	select {
+	case task := <-agents.tasks:
+		task.Timeout = time.Until(task.deadline)
+
+		w.Header().Set("Content-Type", "application/json")
+
+		err := json.NewEncoder(w).Encode(task)
+		if err != nil {
+			log.Println("Send task to agent", r.RemoteAddr,
+				"failed:", err)
+		}
+	case <-time.After(agentPollTimeout):
+		w.WriteHeader(http.StatusNoContent)
+	case <-r.Context().Done():
+	}
+}
+
+// serveResult pass result from agent to waiting checker
+func serveResult(w http.ResponseWriter, r *http.Request) {
+
+	var ar agentResult
+
+	err := json.NewDecoder(http.MaxBytesReader(w, r.Body,
+		maxAgentResultSize)).Decode(&ar)
+	if err != nil {
+		http.Error(w, "Body must be json result", http.StatusBadRequest)
+		return
+	}
+
+	agents.Lock()
+	done, exist := agents.pending[ar.ID]
+	agents.Unlock()
+
+	if !exist {
+		http.Error(w, "Task expired", http.StatusGone)
+		return
+	}
+
+	select {
+	case done <- ar.result():
+	default:
+		// Result already reported
+	}
+}
+
+func agentMux(token string) *http.ServeMux {
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/agent/task", agentAuth(token, serveTask))
+	mux.HandleFunc("/agent/result", agentAuth(token, serveResult))
+
+	return mux
+}
+
+// ServeAgents starts task server for checker agents at addr, since then all
+// checkers run by agents
+func ServeAgents(addr, token string) (err error) {
+
+	if token == "" {
+		err = errors.New("token for checker agents is not set")
+		return
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return
+	}
+
+	log.Println("Launching checker agent server at", addr, "...")
+
+	agents.Lock()
+	agents.enabled = true
+	agents.Unlock()
+
+	go func() {
+		err := http.Serve(listener, agentMux(token))
+		if err != nil {
+			log.Println("Checker agent server error:", err)
+		}
+	}()
+
+	return
+}
+
+// agentPost post json to tin_foil_hat and decode response to out, returns
+// false if there is no content
+func agentPost(ctx context.Context, client *http.Client, url, token string,
+	in, out interface{}) (ok bool, err error) {
+
+	// Empty body, so server notices disconnect while long polling
+	var body io.Reader = http.NoBody
+
+	if in != nil {
+		var buf []byte
+		buf, err = json.Marshal(in)
+		if err != nil {
+			return
+		}
+		body = bytes.NewReader(buf)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, body)
+	if err != nil {
+		return
+	}
+
+	req.Header.Set(agentTokenHeader, token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return
+	}
+
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		ok = true
+		if out != nil {
+			err = json.NewDecoder(resp.Body).Decode(out)
+		}
+	case http.StatusNoContent:
+	default:
+		err = fmt.Errorf("%s: %s", url, resp.Status)
+	}
+
+	return
+}
+
+// agentStep pull one task, run it and report result
+func agentStep(ctx context.Context, client *http.Client, url,
+	token string) (err error) {
+
+	var task agentTask
+
+	ok, err := agentPost(ctx, client, url+"/agent/task", token, nil, &task)
+	if err != nil || !ok {
+		return
+	}
+
+	taskCtx, cancel := context.WithTimeout(ctx, task.Timeout)
+	defer cancel()
+
+	res, err := dispatch(taskCtx, task.Team, task.Service, task.Action,
+		task.Arg, task.Place)
+	if err != nil {
+		// Agent is stopped
+		return
+	}
+
+	_, err = agentPost(ctx, client, url+"/agent/result", token,
+		newAgentResult(task.ID, res), nil)
+	return
+}
+
+// RunAgent pull tasks from tin_foil_hat at url (e.g. "http://10.0.0.1:8100")
+// and run up to workers checkers simultaneously until ctx is done
+func RunAgent(ctx context.Context, url, token string, workers int) {
+
+	if workers <= 0 {
+		workers = defaultAgentWorkers
+	}
+
+	url = strings.TrimRight(url, "/")
+
+	client := &http.Client{Timeout: agentPollTimeout + 10*time.Second}
+
+	log.Println("Checker agent of", url, "started with", workers,
+		"workers")
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for ctx.Err() == nil {
+				err := agentStep(ctx, client, url, token)
+				if err == nil || ctx.Err() != nil {
+					continue
+				}
+
+				log.Println("Checker agent error:", err)
+
+				select {
+				case <-time.After(time.Second):
+				case <-ctx.Done():
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+}
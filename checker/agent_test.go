@@ -0,0 +1,123 @@
+/**
+ * @file agent_test.go
+ * @author Mikhail Klementyev jollheef<AT>riseup.net
+ * @license GNU AGPLv3
+ * @date October, 2026
+ * @brief test remote checker agents
+ */
+
+package checker
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+import "github.com/jollheef/tin_foil_hat/steward"
+
+type agentChecker struct{}
+
+func (agentChecker) Check(ctx context.Context, ip string,
+	port int) (v Verdict, err error) {
+
+	v.State = steward.StatusMumble
+	v.Public = "Checked by agent"
+	return
+}
+
+func (agentChecker) Put(ctx context.Context, ip string, port int,
+	flag string, place int) (v Verdict, err error) {
+
+	v.State = steward.StatusUP
+	v.Cred = flag + ":cred"
+	return
+}
+
+func (agentChecker) Get(ctx context.Context, ip string, port int,
+	cred string, place int) (v Verdict, err error) {
+
+	v.State = steward.StatusUP
+	v.Flag = cred
+	return
+}
+
+func TestAgent(*testing.T) {
+
+	if !Registered("agent") {
+		Register("agent", agentChecker{})
+	}
+
+	srv := httptest.NewServer(agentMux("secret"))
+	defer srv.Close()
+
+	agents.Lock()
+	agents.enabled = true
+	agents.Unlock()
+
+	defer func() {
+		agents.Lock()
+		agents.enabled = false
+		agents.Unlock()
+	}()
+
+	agentCtx, stopAgent := context.WithCancel(context.Background())
+	defer stopAgent()
+
+	stopped := make(chan struct{})
+	go func() {
+		RunAgent(agentCtx, srv.URL, "secret", 2)
+		close(stopped)
+	}()
+
+	ctx := context.Background()
+
+	team := steward.Team{Name: "foo", Vulnbox: "10.0.0.2", Token: "team"}
+	svc := steward.Service{Name: "bar", Checker: "agent"}
+
+	res, err := callCheck(ctx, team, svc)
+	if err != nil {
+		log.Fatalln("Check failed:", err)
+	}
+
+	if res.action != "chk" || res.state != steward.StatusMumble ||
+		res.public != "Checked by agent" {
+		log.Fatalln("Invalid check result:", res)
+	}
+
+	res, err = callPut(ctx, team, svc, "flag", 1)
+	if err != nil {
+		log.Fatalln("Put failed:", err)
+	}
+
+	if res.state != steward.StatusUP || res.cred != "flag:cred" {
+		log.Fatalln("Invalid put result:", res)
+	}
+
+	_, err = agentPost(ctx, http.DefaultClient, srv.URL+"/agent/task",
+		"invalid", nil, nil)
+	if err == nil {
+		log.Fatalln("Invalid token accepted")
+	}
+
+	// Without agents checks are checker errors
+	stopAgent()
+	<-stopped
+
+	agentGrace = 100 * time.Millisecond
+	defer func() { agentGrace = 5 * time.Second }()
+
+	svc.CheckTimeout.Duration = 100 * time.Millisecond
+
+	res, err = callCheck(ctx, team, svc)
+	if err != nil {
+		log.Fatalln("Check failed:", err)
+	}
+
+	if res.state != steward.StatusError {
+		log.Fatalln("Check without agents is not error:", res)
+	}
+}
@@ -11,76 +11,189 @@
 package checker
 
 import (
-	"crypto/rsa"
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
-	"net"
-	"sync"
+	"time"
 
 	"github.com/jollheef/tin_foil_hat/steward"
 	"github.com/jollheef/tin_foil_hat/vexillary"
 )
 
-func tcpPortOpen(team steward.Team, svc steward.Service) bool {
+// Reasons visible to team when checker does not provide them
+const (
+	portClosedReason   = "Port closed"
+	noResponseReason   = "No response"
+	flagNotFoundReason = "Flag not found"
+)
 
-	addr := fmt.Sprintf("%s:%d", team.Vulnbox, svc.Port)
+// places returns amount of flag stores in service
+func places(svc steward.Service) int {
+	if svc.Places < 1 {
+		return 1
+	}
+	return svc.Places
+}
 
-	conn, err := net.DialTimeout("tcp", addr, portCheckTimeout)
-	if err != nil {
-		return false
+// placeArg returns place for checker arguments, services with single place
+// called without it
+func placeArg(svc steward.Service, place int) int {
+	if places(svc) == 1 {
+		return 0
+	}
+	return place
+}
+
+// dispatch run action by native checker of service, or by checker
+// executable (through netbox if team use it), arg is flag for put and cred
+// for get
+func dispatch(ctx context.Context, team steward.Team, svc steward.Service,
+	action, arg string, place int) (res result, err error) {
+
+	native, netbox, json := svc.Checker != "", team.UseNetbox,
+		svc.JSONProtocol
+
+	switch {
+	case action == "put" && native:
+		return nativePut(ctx, svc.Checker, team.Vulnbox, svc.Port, arg,
+			place)
+	case action == "put" && netbox:
+		return sshPut(ctx, team, svc.CheckerPath, team.Vulnbox,
+			svc.Port, arg, placeArg(svc, place), json)
+	case action == "put":
+		return put(ctx, svc.CheckerPath, team.Vulnbox, svc.Port, arg,
+			placeArg(svc, place), json)
+
+	case action == "get" && native:
+		return nativeGet(ctx, svc.Checker, team.Vulnbox, svc.Port, arg,
+			place)
+	case action == "get" && netbox:
+		return sshGet(ctx, team, svc.CheckerPath, team.Vulnbox,
+			svc.Port, arg, placeArg(svc, place), json)
+	case action == "get":
+		return get(ctx, svc.CheckerPath, team.Vulnbox, svc.Port, arg,
+			placeArg(svc, place), json)
+
+	case native:
+		return nativeCheck(ctx, svc.Checker, team.Vulnbox, svc.Port)
+	case netbox:
+		return sshCheck(ctx, team, svc.CheckerPath, team.Vulnbox,
+			svc.Port, json)
 	}
 
-	conn.Close()
-	return true
+	return check(ctx, svc.CheckerPath, team.Vulnbox, svc.Port, json)
 }
 
-func putFlag(db *sql.DB, priv *rsa.PrivateKey, round int, team steward.Team,
-	svc steward.Service) (err error) {
+// call run action with timeout of service, on checker agent if agents are
+// enabled
+func call(ctx context.Context, team steward.Team, svc steward.Service,
+	action, arg string, place int) (res result, err error) {
 
-	flag, err := vexillary.GenerateFlag(priv)
-	if err != nil {
-		log.Println("Generate flag failed:", err)
-		return
-	}
+	ctx, cancel := actionContext(ctx, svc, action)
+	defer cancel()
 
-	portOpen := true
-	if !svc.UDP {
-		portOpen = tcpPortOpen(team, svc)
+	if agentsEnabled() {
+		return callAgent(ctx, team, svc, action, arg, place)
 	}
 
-	var cred, logs string
-	var state steward.ServiceState
-	if portOpen {
-		if team.UseNetbox {
-			cred, logs, state, err = sshPut(team.Netbox,
-				svc.CheckerPath, team.Vulnbox, svc.Port, flag)
-		} else {
-			cred, logs, state, err = put(svc.CheckerPath,
-				team.Vulnbox, svc.Port, flag)
-		}
+	return dispatch(ctx, team, svc, action, arg, place)
+}
+
+// callPut put flag to service
+func callPut(ctx context.Context, team steward.Team, svc steward.Service,
+	flag string, place int) (res result, err error) {
+
+	return call(ctx, team, svc, "put", flag, place)
+}
+
+// callGet get flag from service
+func callGet(ctx context.Context, team steward.Team, svc steward.Service,
+	cred string, place int) (res result, err error) {
+
+	return call(ctx, team, svc, "get", cred, place)
+}
+
+// callCheck check service logic
+func callCheck(ctx context.Context, team steward.Team,
+	svc steward.Service) (res result, err error) {
+
+	return call(ctx, team, svc, "chk", "", 0)
+}
+
+// amount of attempts to generate flag which is not in database, nonce of
+// flags with embedded info is short and can collide
+const flagAttempts = 3
+
+// newFlag generate flag which does not exist in database
+func newFlag(db *sql.DB, scheme vexillary.Scheme,
+	info vexillary.Info) (flag string, err error) {
+
+	for attempt := 0; attempt < flagAttempts; attempt++ {
+
+		flag, err = vexillary.NewFlag(scheme, info)
 		if err != nil {
-			log.Println("Put flag to service failed:", err)
 			return
 		}
 
-		if state != steward.StatusUP {
-			log.Printf("Put flag, round %d, team %s, service %s: %s",
-				round, team.Name, svc.Name, logs)
+		var exist bool
+		exist, err = steward.FlagExist(db, flag)
+		if err != nil || !exist {
+			return
+		}
+	}
+
+	err = errors.New("cannot generate unique flag")
+	return
+}
+
+func putFlag(ctx context.Context, db *sql.DB, scheme vexillary.Scheme,
+	round int, team steward.Team, svc steward.Service,
+	place int) (err error) {
+
+	flag, err := newFlag(db, scheme, vexillary.Info{Round: round,
+		TeamID: team.ID, ServiceID: svc.ID, Place: place})
+	if err != nil {
+		log.Println("Generate flag failed:", err)
+		return
+	}
+
+	res, err := retry(ctx, team, svc, func() (result, error) {
+		if !portOpen(ctx, team, svc) {
+			return portClosed(svc), nil
 		}
-	} else {
-		state = steward.StatusDown
+		return callPut(ctx, team, svc, flag, place)
+	})
+	if err != nil {
+		log.Println("Put flag to service failed:", err)
+		return
+	}
+
+	if res.state != steward.StatusUP {
+		log.Printf("Put flag, round %d, team %s, service %s, "+
+			"place %d: %s", round, team.Name, svc.Name, place,
+			res.logs)
 	}
 
-	err = steward.PutStatus(db,
-		steward.Status{round, team.ID, svc.ID, state})
+	if ctx.Err() != nil {
+		// Round is over, flag is not put
+		err = ctx.Err()
+		return
+	}
+
+	res.place = place
+
+	err = steward.PutStatus(db, res.status(round, team, svc))
 	if err != nil {
 		log.Println("Add status to database failed:", err)
 		return
 	}
 
 	err = steward.AddFlag(db,
-		steward.Flag{-1, flag, round, team.ID, svc.ID, cred})
+		steward.Flag{ID: -1, Flag: flag, Round: round,
+			TeamID: team.ID, ServiceID: svc.ID, Cred: res.cred,
+			Place: place, PublicID: res.flagID})
 	if err != nil {
 		log.Println("Add flag to database failed:", err)
 		return
@@ -89,135 +202,206 @@ func putFlag(db *sql.DB, priv *rsa.PrivateKey, round int, team steward.Team,
 	return
 }
 
-func getFlag(db *sql.DB, round int, team steward.Team,
-	svc steward.Service) (state steward.ServiceState, err error) {
+var flagLifetime = 1
+
+// SetFlagLifetime set amount of rounds while flag must be retrievable from
+// service, including round of flag
+func SetFlagLifetime(rounds int) {
+	flagLifetime = rounds
+}
+
+var oldFlags = 1
+
+// SetOldFlags set amount of random alive flags from previous rounds which
+// checked in addition to flag of current round
+func SetOldFlags(count int) {
+	oldFlags = count
+}
+
+func getFlag(ctx context.Context, db *sql.DB, round, flagRound int,
+	team steward.Team, svc steward.Service, place int) (res result,
+	err error) {
 
-	flag, cred, err := steward.GetCred(db, round, team.ID, svc.ID)
+	res.action = "get"
+	res.place = place
+
+	if flagRound <= round-flagLifetime || flagRound > round {
+		err = fmt.Errorf("flag of round %d expired in round %d",
+			flagRound, round)
+		res.state = steward.StatusError
+		return
+	}
+
+	flag, cred, err := steward.GetCred(db, flagRound, team.ID, svc.ID,
+		place)
 	if err != nil {
 		log.Println("Get cred failed:", err)
-		state = steward.StatusCorrupt
+		res.state = steward.StatusCorrupt
+		res.public = flagNotFoundReason
 		return
 	}
 
-	var logs string
-	var serviceFlag string
-
-	if team.UseNetbox {
-		serviceFlag, logs, state, err = sshGet(team.Netbox,
-			svc.CheckerPath, team.Vulnbox, svc.Port, cred)
-	} else {
-		serviceFlag, logs, state, err = get(svc.CheckerPath,
-			team.Vulnbox, svc.Port, cred)
-	}
+	res, err = callGet(ctx, team, svc, cred, place)
+	res.place = place
 	if err != nil {
 		log.Println("Check service failed:", err)
 		return
 	}
 
-	if flag != serviceFlag {
-		state = steward.StatusCorrupt
+	if res.state == steward.StatusUP && flag != res.flag {
+		res.state = steward.StatusCorrupt
+		res.public = flagNotFoundReason
 	}
 
-	if state != steward.StatusUP {
-		log.Printf("Get flag, round %d, team %s, service %s: %s",
-			round, team.Name, svc.Name, logs)
+	if res.state != steward.StatusUP {
+		log.Printf("Get flag, round %d, team %s, service %s, "+
+			"place %d: %s", round, team.Name, svc.Name, place,
+			res.logs)
 	}
 
 	return
 }
 
-func checkService(db *sql.DB, round int, team steward.Team,
-	svc steward.Service) (state steward.ServiceState, err error) {
+func checkService(ctx context.Context, db *sql.DB, round int,
+	team steward.Team, svc steward.Service) (res result, err error) {
 
-	var logs string
-
-	if team.UseNetbox {
-		state, logs, err = sshCheck(team.Netbox, svc.CheckerPath,
-			team.Vulnbox, svc.Port)
-	} else {
-		state, logs, err = check(svc.CheckerPath, team.Vulnbox,
-			svc.Port)
-	}
+	res, err = callCheck(ctx, team, svc)
 	if err != nil {
 		log.Println("Check service failed:", err)
 		return
 	}
 
-	if state != steward.StatusUP {
+	if res.state != steward.StatusUP {
 		log.Printf("Check, round %d, team %s, service %s: %s",
-			round, team.Name, svc.Name, logs)
+			round, team.Name, svc.Name, res.logs)
 	}
 
 	return
 }
 
-// Check service status and flag if it's exist.
-func checkFlag(db *sql.DB, round int, team steward.Team, svc steward.Service,
-	wg *sync.WaitGroup) {
+// Check random alive flags from previous rounds, returns result of last
+// checked flag with total duration, empty action means nothing was checked
+func getOldFlags(ctx context.Context, db *sql.DB, round int,
+	team steward.Team, svc steward.Service) (res result, err error) {
 
-	defer wg.Done()
+	res.state = steward.StatusUP
 
-	// Check service port open
-	portOpen := true
-	if !svc.UDP {
-		portOpen = tcpPortOpen(team, svc)
-	}
-
-	var state steward.ServiceState
-	if portOpen {
-		// First check service logic
-		state, _ = checkService(db, round, team, svc)
-		if state == steward.StatusUP {
-			// If logic is correct, do flag check
-			state, _ = getFlag(db, round, team, svc)
-		}
-	} else {
-		state = steward.StatusDown
+	if oldFlags <= 0 || flagLifetime <= 1 {
+		return
 	}
 
-	err := steward.PutStatus(db, steward.Status{round,
-		team.ID, svc.ID, state})
+	flags, err := steward.GetRandomFlags(db, team.ID, svc.ID,
+		round-flagLifetime+1, round, oldFlags)
 	if err != nil {
-		log.Println("Add status failed:", err)
+		log.Println("Get random flags failed:", err)
+		res.action = "get"
+		res.state = steward.StatusError
+		res.logs = err.Error()
 		return
 	}
+
+	var duration time.Duration
+
+	for _, flag := range flags {
+		res, err = getFlag(ctx, db, round, flag.Round, team, svc,
+			flag.Place)
+		duration += res.duration
+		res.duration = duration
+
+		if res.state != steward.StatusUP &&
+			res.state != steward.StatusError {
+			// Flag put earlier cannot be retrieved
+			res.state = steward.StatusCorrupt
+			if res.public == "" {
+				res.public = flagNotFoundReason
+			}
+		}
+		if res.state != steward.StatusUP {
+			return
+		}
+	}
+
+	return
 }
 
-// PutFlags put flags to services
-func PutFlags(db *sql.DB, priv *rsa.PrivateKey, round int,
-	teams []steward.Team, services []steward.Service) (err error) {
+// checkAll check service logic and flags, returns first failed result
+func checkAll(ctx context.Context, db *sql.DB, round int,
+	team steward.Team, svc steward.Service) (res result) {
 
-	var wg sync.WaitGroup
+	// Check service port open
+	if !portOpen(ctx, team, svc) {
+		return portClosed(svc)
+	}
 
-	for _, team := range teams {
-		for _, svc := range services {
-			wg.Add(1)
-			go func(team steward.Team, svc steward.Service) {
-				defer wg.Done()
-				putFlag(db, priv, round, team, svc)
-			}(team, svc)
+	// First check service logic
+	res, _ = checkService(ctx, db, round, team, svc)
+	duration := res.duration
+	// If logic is correct, do flag check in each place
+	for place := 1; place <= places(svc); place++ {
+		if res.state != steward.StatusUP {
+			break
 		}
+		res, _ = getFlag(ctx, db, round, round, team, svc, place)
+		duration += res.duration
 	}
-
-	wg.Wait()
+	if res.state == steward.StatusUP {
+		// Previous flags must be alive too
+		old, _ := getOldFlags(ctx, db, round, team, svc)
+		if old.action != "" {
+			res = old
+			duration += old.duration
+		}
+	}
+	res.duration = duration
 
 	return
 }
 
-// CheckFlags check flags in services
-func CheckFlags(db *sql.DB, round int, teams []steward.Team,
-	services []steward.Service) (err error) {
+// Check service status and flag if it's exist.
+func checkFlag(ctx context.Context, db *sql.DB, round int,
+	team steward.Team, svc steward.Service) {
 
-	var wg sync.WaitGroup
+	res, _ := retry(ctx, team, svc, func() (result, error) {
+		return checkAll(ctx, db, round, team, svc), nil
+	})
 
-	for _, team := range teams {
-		for _, svc := range services {
-			wg.Add(1)
-			go checkFlag(db, round, team, svc, &wg)
-		}
+	if ctx.Err() != nil {
+		// Round is over, check result is incomplete
+		return
+	}
+
+	err := steward.PutStatus(db, res.status(round, team, svc))
+	if err != nil {
+		log.Println("Add status failed:", err)
+		return
 	}
+}
+
+// PutFlags put flags to services, unfinished checkers are killed when ctx
+// is cancelled
+func PutFlags(ctx context.Context, db *sql.DB, scheme vexillary.Scheme,
+	round int, teams []steward.Team,
+	services []steward.Service) (err error) {
+
+	forEach(ctx, teams, services, func(team steward.Team,
+		svc steward.Service) {
+		for place := 1; place <= places(svc); place++ {
+			putFlag(ctx, db, scheme, round, team, svc, place)
+		}
+	})
+
+	return
+}
+
+// CheckFlags check flags in services, unfinished checkers are killed when
+// ctx is cancelled
+func CheckFlags(ctx context.Context, db *sql.DB, round int,
+	teams []steward.Team, services []steward.Service) (err error) {
 
-	wg.Wait()
+	forEach(ctx, teams, services, func(team steward.Team,
+		svc steward.Service) {
+		checkFlag(ctx, db, round, team, svc)
+	})
 
 	return
 }
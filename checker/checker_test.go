@@ -9,6 +9,7 @@
 package checker_test
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
@@ -165,9 +166,14 @@ func TestFlagsWork(t *testing.T) {
 	service := newDummyService("python-api/dummy_service.py", port)
 	service.Stop() // if already run
 
-	priv, err := vexillary.GenerateKey()
+	secret, err := vexillary.GenerateSecret()
 	if err != nil {
-		log.Fatalln("Generate key failed:", err)
+		log.Fatalln("Generate secret failed:", err)
+	}
+
+	scheme, err := vexillary.NewHMAC(secret, vexillary.DefaultFormat)
+	if err != nil {
+		log.Fatalln("Create flag scheme failed:", err)
 	}
 
 	fillTestTeams(db.db)
@@ -199,7 +205,7 @@ func TestFlagsWork(t *testing.T) {
 		log.Fatalln("Get services failed:", err)
 	}
 
-	err = checker.PutFlags(db.db, priv, round, teams, services)
+	err = checker.PutFlags(context.Background(), db.db, scheme, round, teams, services)
 	if err != nil {
 		log.Fatalln("Put flags failed:", err)
 	}
@@ -214,7 +220,7 @@ func TestFlagsWork(t *testing.T) {
 
 	service.BrokeLogic()
 
-	err = checker.PutFlags(db.db, priv, round, teams, services)
+	err = checker.PutFlags(context.Background(), db.db, scheme, round, teams, services)
 	if err != nil {
 		log.Fatalln("Put flags failed:", err)
 	}
@@ -232,7 +238,7 @@ func TestFlagsWork(t *testing.T) {
 
 	log.Println("Put flags to correct service...")
 
-	err = checker.PutFlags(db.db, priv, round, teams, services)
+	err = checker.PutFlags(context.Background(), db.db, scheme, round, teams, services)
 	if err != nil {
 		log.Fatalln("Put flags failed:", err)
 	}
@@ -241,7 +247,7 @@ func TestFlagsWork(t *testing.T) {
 
 	log.Println("Check flags of correct service...")
 
-	err = checker.CheckFlags(db.db, round, teams, services)
+	err = checker.CheckFlags(context.Background(), db.db, round, teams, services)
 	if err != nil {
 		log.Fatalln("Check flags failed:", err)
 	}
@@ -252,19 +258,31 @@ func TestFlagsWork(t *testing.T) {
 
 	log.Println("Check flags of service with removed flags...")
 
-	err = checker.CheckFlags(db.db, round, teams, services)
+	err = checker.CheckFlags(context.Background(), db.db, round, teams, services)
 	if err != nil {
 		log.Fatalln("Check flags failed:", err)
 	}
 
 	checkServicesStatus(db.db, round, teams, services, steward.StatusCorrupt)
 
+	// Failed get is stored with place of flag
+	statuses, err := steward.GetStatuses(db.db, round, teams[0].ID,
+		services[0].ID)
+	if err != nil || len(statuses) == 0 {
+		log.Fatalln("Get statuses failed:", err)
+	}
+
+	last := statuses[len(statuses)-1]
+	if last.Action != "get" || last.Place != 1 {
+		log.Fatalln("Invalid status of get:", last)
+	}
+
 	log.Println("Stop service...")
 	service.Stop()
 
 	log.Println("Check flags of stopped service...")
 
-	err = checker.CheckFlags(db.db, round, teams, services)
+	err = checker.CheckFlags(context.Background(), db.db, round, teams, services)
 	if err != nil {
 		log.Fatalln("Check flags failed:", err)
 	}
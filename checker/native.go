@@ -0,0 +1,180 @@
+/**
+ * @file native.go
+ * @author Mikhail Klementyev jollheef<AT>riseup.net
+ * @license GNU AGPLv3
+ * @date October, 2026
+ * @brief in-process checkers
+ *
+ * Provide interface for checkers written in go, which registered by name
+ * and called without spawn of processes.
+ */
+
+package checker
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jollheef/tin_foil_hat/steward"
+)
+
+// Verdict of native checker
+type Verdict struct {
+	State  steward.ServiceState
+	Public string // message visible to team
+	Logs   string // private logs, visible only to organizers
+	FlagID string // public id of flag, e.g. user name (only for put)
+	Cred   string // credentials for get flag (only for put)
+	Flag   string // flag received from service (only for get)
+}
+
+// Checker of service running in-process, context is cancelled when checker
+// time is over
+type Checker interface {
+	Check(ctx context.Context, ip string, port int) (Verdict, error)
+	Put(ctx context.Context, ip string, port int, flag string,
+		place int) (Verdict, error)
+	Get(ctx context.Context, ip string, port int, cred string,
+		place int) (Verdict, error)
+}
+
+var natives = struct {
+	sync.RWMutex
+	checkers map[string]Checker
+}{checkers: make(map[string]Checker)}
+
+// Register native checker, services refer to it by name
+func Register(name string, c Checker) {
+
+	natives.Lock()
+	defer natives.Unlock()
+
+	if _, exist := natives.checkers[name]; exist {
+		panic("checker " + name + " already registered")
+	}
+
+	natives.checkers[name] = c
+}
+
+// Registered returns true if native checker with name exist
+func Registered(name string) bool {
+
+	natives.RLock()
+	defer natives.RUnlock()
+
+	_, exist := natives.checkers[name]
+	return exist
+}
+
+func lookupNative(name string) (c Checker, err error) {
+
+	natives.RLock()
+	defer natives.RUnlock()
+
+	c, exist := natives.checkers[name]
+	if !exist {
+		err = fmt.Errorf("checker %s is not registered", name)
+	}
+
+	return
+}
+
+// verdict of native checker with its error, sent by checker goroutine
+type nativeResult struct {
+	v   Verdict
+	err error
+}
+
+// native call checker action, panics and errors of checker are treated as
+// checker errors, checker which ignores ctx is abandoned when ctx is done
+func native(ctx context.Context, name, action string,
+	call func(ctx context.Context, c Checker) (Verdict, error)) (res result,
+	err error) {
+
+	res.action = action
+
+	c, err := lookupNative(name)
+	if err != nil {
+		res.state = steward.StatusError
+		res.logs = err.Error()
+		err = nil
+		return
+	}
+
+	start := time.Now()
+
+	done := make(chan nativeResult, 1)
+	go func() {
+		var nr nativeResult
+		defer func() {
+			if r := recover(); r != nil {
+				nr.err = fmt.Errorf("checker panic: %v", r)
+			}
+			done <- nr
+		}()
+		nr.v, nr.err = call(ctx, c)
+	}()
+
+	var nr nativeResult
+	select {
+	case nr = <-done:
+	case <-ctx.Done():
+		res.duration = time.Since(start)
+		err = timedOut(ctx, &res)
+		return
+	}
+
+	v, cerr := nr.v, nr.err
+
+	res.duration = time.Since(start)
+
+	if ctx.Err() != nil {
+		res.logs = v.Logs
+		err = timedOut(ctx, &res)
+		return
+	}
+
+	if cerr != nil {
+		res.state = steward.StatusError
+		res.logs = cerr.Error()
+		return
+	}
+
+	res.state = v.State
+	res.public = v.Public
+	res.logs = v.Logs
+	res.flagID = v.FlagID
+	res.cred = v.Cred
+	res.flag = v.Flag
+
+	return
+}
+
+func nativePut(ctx context.Context, name, ip string, port int, flag string,
+	place int) (res result, err error) {
+
+	return native(ctx, name, "put", func(ctx context.Context,
+		c Checker) (Verdict, error) {
+		return c.Put(ctx, ip, port, flag, place)
+	})
+}
+
+func nativeGet(ctx context.Context, name, ip string, port int, cred string,
+	place int) (res result, err error) {
+
+	return native(ctx, name, "get", func(ctx context.Context,
+		c Checker) (Verdict, error) {
+		return c.Get(ctx, ip, port, cred, place)
+	})
+}
+
+func nativeCheck(ctx context.Context, name, ip string, port int) (res result,
+	err error) {
+
+	return native(ctx, name, "chk", func(ctx context.Context,
+		c Checker) (Verdict, error) {
+		return c.Check(ctx, ip, port)
+	})
+}
@@ -0,0 +1,154 @@
+/**
+ * @file native_test.go
+ * @author Mikhail Klementyev jollheef<AT>riseup.net
+ * @license GNU AGPLv3
+ * @date October, 2026
+ * @brief test in-process checkers
+ */
+
+package checker
+
+import (
+	"context"
+	"errors"
+	"log"
+	"testing"
+	"time"
+)
+
+import "github.com/jollheef/tin_foil_hat/steward"
+
+type testChecker struct {
+	flags map[string]string // { cred : flag }
+	block chan struct{}     // checker which ignores ctx waits for it
+}
+
+func (c testChecker) Check(ctx context.Context, ip string,
+	port int) (v Verdict, err error) {
+
+	switch port {
+	case 1:
+		panic("oops")
+	case 2:
+		err = errors.New("broken checker")
+	case 3:
+		<-ctx.Done()
+		v.State = steward.StatusUP
+	case 4:
+		<-c.block
+		v.State = steward.StatusUP
+	default:
+		v.State = steward.StatusMumble
+		v.Public = "Wrong banner"
+	}
+
+	return
+}
+
+func (c testChecker) Put(ctx context.Context, ip string, port int,
+	flag string, place int) (v Verdict, err error) {
+
+	cred := ip + ":" + string(rune('0'+place))
+
+	c.flags[cred] = flag
+
+	v.State = steward.StatusUP
+	v.Cred = cred
+	v.FlagID = "user"
+
+	return
+}
+
+func (c testChecker) Get(ctx context.Context, ip string, port int,
+	cred string, place int) (v Verdict, err error) {
+
+	v.State = steward.StatusUP
+	v.Flag = c.flags[cred]
+
+	return
+}
+
+func TestNativeChecker(*testing.T) {
+
+	block := make(chan struct{})
+	defer close(block)
+
+	Register("test", testChecker{flags: make(map[string]string),
+		block: block})
+
+	if !Registered("test") || Registered("unknown") {
+		log.Fatalln("Invalid registered checkers")
+	}
+
+	ctx := context.Background()
+
+	team := steward.Team{Vulnbox: "10.0.0.2"}
+	svc := steward.Service{Checker: "test", Places: 2}
+
+	res, err := callPut(ctx, team, svc, "flag", 2)
+	if err != nil {
+		log.Fatalln("Put failed:", err)
+	}
+
+	if res.action != "put" || res.state != steward.StatusUP ||
+		res.cred != "10.0.0.2:2" || res.flagID != "user" {
+		log.Fatalln("Invalid put result:", res)
+	}
+
+	res, err = callGet(ctx, team, svc, res.cred, 2)
+	if err != nil {
+		log.Fatalln("Get failed:", err)
+	}
+
+	if res.action != "get" || res.flag != "flag" {
+		log.Fatalln("Invalid get result:", res)
+	}
+
+	res, _ = callCheck(ctx, team, svc)
+	if res.state != steward.StatusMumble || res.public != "Wrong banner" {
+		log.Fatalln("Invalid check result:", res)
+	}
+
+	svc.Port = 1
+	res, _ = callCheck(ctx, team, svc)
+	if res.state != steward.StatusError {
+		log.Fatalln("Panic is not checker error:", res)
+	}
+
+	svc.Port = 2
+	res, _ = callCheck(ctx, team, svc)
+	if res.state != steward.StatusError {
+		log.Fatalln("Error is not checker error:", res)
+	}
+
+	svc.Port = 3
+	svc.CheckTimeout.Duration = 100 * time.Millisecond
+	res, _ = callCheck(ctx, team, svc)
+	if res.state != steward.StatusDown || res.public != timeoutReason {
+		log.Fatalln("Timeout is not down:", res)
+	}
+
+	// Checker which ignores ctx does not block caller after timeout
+	svc.Port = 4
+	start := time.Now()
+	res, _ = callCheck(ctx, team, svc)
+	if res.state != steward.StatusDown || res.public != timeoutReason ||
+		time.Since(start) > time.Second {
+		log.Fatalln("Checker ignoring ctx is not timed out:", res,
+			time.Since(start))
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+
+	_, err = callCheck(cancelled, team, svc)
+	if err == nil {
+		log.Fatalln("Cancelled check is not failed")
+	}
+
+	svc.Checker = "unknown"
+	res, _ = callCheck(ctx, team, svc)
+	if res.state != steward.StatusError {
+		log.Fatalln("Unknown checker is not checker error:", res)
+	}
+}
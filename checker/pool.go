@@ -0,0 +1,144 @@
+/**
+ * @file pool.go
+ * @author Mikhail Klementyev jollheef<AT>riseup.net
+ * @license GNU AGPLv3
+ * @date October, 2026
+ * @brief bounded pool of checker workers
+ *
+ * Provide function for run checkers of all teams and services with limited
+ * amount of simultaneous checkers in total and per service.
+ */
+
+package checker
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+
+	"github.com/jollheef/tin_foil_hat/steward"
+)
+
+var (
+	workers        = 0 // max simultaneous checkers, zero is unlimited
+	serviceWorkers = 0 // max simultaneous checkers of service by default
+)
+
+// SetWorkers set max amount of simultaneous checkers in total and per
+// service (if service has no own limit), zero means no limit
+func SetWorkers(total, perService int) {
+	workers = total
+	serviceWorkers = perService
+}
+
+type task struct {
+	team steward.Team
+	svc  steward.Service
+}
+
+// serviceLimit returns max simultaneous checkers of service
+func serviceLimit(svc steward.Service) int {
+	if svc.Workers > 0 {
+		return svc.Workers
+	}
+	return serviceWorkers
+}
+
+// queue of tasks shared by workers, task is taken only if its service has
+// free slot, so busy service does not hold workers of others
+type queue struct {
+	mutex   sync.Mutex
+	cond    *sync.Cond
+	tasks   []task
+	running map[int]int
+}
+
+func newQueue(tasks []task) (q *queue) {
+	q = &queue{tasks: tasks, running: make(map[int]int)}
+	q.cond = sync.NewCond(&q.mutex)
+	return
+}
+
+// take first task with free service slot, waits until some task is done
+// if all services of left tasks are busy, returns false if queue is empty
+// or ctx is done
+func (q *queue) take(ctx context.Context) (t task, ok bool) {
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	for len(q.tasks) != 0 && ctx.Err() == nil {
+		for i, t := range q.tasks {
+			limit := serviceLimit(t.svc)
+			if limit > 0 && q.running[t.svc.ID] >= limit {
+				continue
+			}
+
+			q.tasks = append(q.tasks[:i], q.tasks[i+1:]...)
+			q.running[t.svc.ID]++
+			return t, true
+		}
+
+		q.cond.Wait()
+	}
+
+	return
+}
+
+// done release service slot of task
+func (q *queue) done(t task) {
+
+	q.mutex.Lock()
+	q.running[t.svc.ID]--
+	q.mutex.Unlock()
+
+	q.cond.Broadcast()
+}
+
+// forEach call fn for each team and service in random order, so teams from
+// the start of list are not always checked first, and wait for all calls,
+// tasks not started before ctx is done are skipped
+func forEach(ctx context.Context, teams []steward.Team,
+	services []steward.Service,
+	fn func(team steward.Team, svc steward.Service)) {
+
+	var tasks []task
+	for _, team := range teams {
+		for _, svc := range services {
+			tasks = append(tasks, task{team, svc})
+		}
+	}
+
+	rand.Shuffle(len(tasks), func(i, j int) {
+		tasks[i], tasks[j] = tasks[j], tasks[i]
+	})
+
+	amount := len(tasks)
+	if workers > 0 && workers < amount {
+		amount = workers
+	}
+
+	q := newQueue(tasks)
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < amount; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for {
+				t, ok := q.take(ctx)
+				if !ok {
+					return
+				}
+
+				fn(t.team, t.svc)
+
+				q.done(t)
+			}
+		}()
+	}
+
+	wg.Wait()
+}
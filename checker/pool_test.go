@@ -0,0 +1,102 @@
+/**
+ * @file pool_test.go
+ * @author Mikhail Klementyev jollheef<AT>riseup.net
+ * @license GNU AGPLv3
+ * @date October, 2026
+ * @brief test pool of checker workers
+ */
+
+package checker
+
+import (
+	"context"
+	"log"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+import "github.com/jollheef/tin_foil_hat/steward"
+
+func TestForEach(*testing.T) {
+
+	SetWorkers(4, 2)
+	defer SetWorkers(0, 0)
+
+	var teams []steward.Team
+	for i := 1; i <= 10; i++ {
+		teams = append(teams, steward.Team{ID: i})
+	}
+
+	var services []steward.Service
+	for i := 1; i <= 3; i++ {
+		services = append(services, steward.Service{ID: i})
+	}
+
+	// Own limit of service is used instead of global
+	services[2].Workers = 1
+
+	limits := map[int]int{1: 2, 2: 2, 3: 1}
+
+	goroutines := runtime.NumGoroutine()
+
+	var mutex sync.Mutex
+	var running, maxRunning, maxGoroutines int
+	runningService := make(map[int]int)
+	checked := make(map[task]bool)
+
+	forEach(context.Background(), teams, services, func(team steward.Team,
+		svc steward.Service) {
+
+		mutex.Lock()
+		running++
+		runningService[svc.ID]++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		if runningService[svc.ID] > limits[svc.ID] {
+			log.Fatalln("Service limit exceeded:", svc.ID)
+		}
+		if runtime.NumGoroutine() > maxGoroutines {
+			maxGoroutines = runtime.NumGoroutine()
+		}
+		checked[task{team, svc}] = true
+		mutex.Unlock()
+
+		time.Sleep(time.Millisecond)
+
+		mutex.Lock()
+		running--
+		runningService[svc.ID]--
+		mutex.Unlock()
+	})
+
+	if maxRunning > 4 {
+		log.Fatalln("Total limit exceeded:", maxRunning)
+	}
+
+	// Fixed amount of workers instead of goroutine per task
+	if maxGoroutines > goroutines+4 {
+		log.Fatalln("Too many goroutines:", maxGoroutines-goroutines)
+	}
+
+	if len(checked) != len(teams)*len(services) {
+		log.Fatalln("Checked", len(checked), "instead",
+			len(teams)*len(services))
+	}
+}
+
+func TestForEachCancelled(*testing.T) {
+
+	teams := []steward.Team{{ID: 1}}
+	services := []steward.Service{{ID: 1}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	forEach(ctx, teams, services, func(team steward.Team,
+		svc steward.Service) {
+		log.Fatalln("Task started after cancel")
+	})
+}
@@ -0,0 +1,137 @@
+/**
+ * @file probe.go
+ * @author Mikhail Klementyev jollheef<AT>riseup.net
+ * @license GNU AGPLv3
+ * @date October, 2026
+ * @brief fast liveness probes of service port
+ *
+ * Provide functions for check that service port is open (tcp) or service
+ * responds to probe (udp) before run of checker.
+ */
+
+package checker
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"regexp"
+	"time"
+
+	"github.com/jollheef/tin_foil_hat/steward"
+)
+
+const (
+	udpProbeAttempts = 2 // probe resent, because datagram may be lost
+	maxDatagramSize  = 65535
+)
+
+// portClosed returns result for service with closed tcp port or udp
+// service without response to probe
+func portClosed(svc steward.Service) result {
+
+	if svc.UDP {
+		return result{action: "probe", state: steward.StatusDown,
+			public: noResponseReason}
+	}
+
+	return result{action: "connect", state: steward.StatusDown,
+		public: portClosedReason}
+}
+
+// portOpen returns false if service is surely down, udp services without
+// probe are always open
+func portOpen(ctx context.Context, team steward.Team,
+	svc steward.Service) bool {
+
+	if !svc.UDP {
+		return tcpPortOpen(ctx, team, svc)
+	}
+
+	if svc.UDPProbe == "" {
+		return true
+	}
+
+	return udpResponds(ctx, team, svc)
+}
+
+func tcpPortOpen(ctx context.Context, team steward.Team,
+	svc steward.Service) bool {
+
+	addr := fmt.Sprintf("%s:%d", team.Vulnbox, svc.Port)
+
+	dialer := net.Dialer{Timeout: portCheckTimeout}
+
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return false
+	}
+
+	conn.Close()
+	return true
+}
+
+// udpResponds send probe to udp service and wait for response which match
+// probe response regexp (any response if it's empty)
+func udpResponds(ctx context.Context, team steward.Team,
+	svc steward.Service) bool {
+
+	response, err := regexp.Compile(svc.UDPProbeResponse)
+	if err != nil {
+		// Misconfigured probe is not a fault of team
+		log.Println("Invalid probe response of service", svc.Name, err)
+		return true
+	}
+
+	probeTimeout := svc.UDPProbeTimeout.Duration
+	if probeTimeout == 0 {
+		probeTimeout = portCheckTimeout
+	}
+
+	addr := net.JoinHostPort(team.Vulnbox, fmt.Sprintf("%d", svc.Port))
+
+	var dialer net.Dialer
+
+	conn, err := dialer.DialContext(ctx, "udp", addr)
+	if err != nil {
+		return false
+	}
+
+	defer conn.Close()
+
+	buf := make([]byte, maxDatagramSize)
+
+	for attempt := 0; attempt < udpProbeAttempts; attempt++ {
+		deadline := time.Now().Add(probeTimeout / udpProbeAttempts)
+		if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+			deadline = d
+		}
+		conn.SetDeadline(deadline)
+
+		_, err = conn.Write([]byte(svc.UDPProbe))
+		if err != nil {
+			return false
+		}
+
+		// Skip responses which not match, e.g. late response for
+		// previous attempt
+		for {
+			var n int
+			n, err = conn.Read(buf)
+			if err != nil {
+				break
+			}
+
+			if response.Match(buf[:n]) {
+				return true
+			}
+		}
+
+		if ctx.Err() != nil {
+			return false
+		}
+	}
+
+	return false
+}
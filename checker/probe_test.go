@@ -0,0 +1,77 @@
+/**
+ * @file probe_test.go
+ * @author Mikhail Klementyev jollheef<AT>riseup.net
+ * @license GNU AGPLv3
+ * @date October, 2026
+ * @brief test liveness probes
+ */
+
+package checker
+
+import (
+	"context"
+	"log"
+	"net"
+	"testing"
+	"time"
+)
+
+import "github.com/jollheef/tin_foil_hat/steward"
+
+func TestUDPProbe(*testing.T) {
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		log.Fatalln("Listen failed:", err)
+	}
+
+	defer conn.Close()
+
+	go func() {
+		buf := make([]byte, 1024)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if string(buf[:n]) == "ping\n" {
+				conn.WriteTo([]byte("pong\n"), addr)
+			}
+		}
+	}()
+
+	ctx := context.Background()
+
+	team := steward.Team{Vulnbox: "127.0.0.1"}
+	svc := steward.Service{UDP: true,
+		Port:             conn.LocalAddr().(*net.UDPAddr).Port,
+		UDPProbe:         "ping\n",
+		UDPProbeResponse: "^pong",
+		UDPProbeTimeout:  steward.Timeout{Duration: time.Second}}
+
+	if !portOpen(ctx, team, svc) {
+		log.Fatalln("Responding service is not open")
+	}
+
+	svc.UDPProbeResponse = "^PONG"
+	if portOpen(ctx, team, svc) {
+		log.Fatalln("Wrong response is accepted")
+	}
+
+	svc.UDPProbeResponse = ""
+	svc.UDPProbe = "hello\n"
+	if portOpen(ctx, team, svc) {
+		log.Fatalln("Service without response is open")
+	}
+
+	res := portClosed(svc)
+	if res.state != steward.StatusDown || res.public != noResponseReason {
+		log.Fatalln("Invalid result:", res)
+	}
+
+	// Without probe udp services judged by checker only
+	svc.UDPProbe = ""
+	if !portOpen(ctx, team, svc) {
+		log.Fatalln("Service without probe is not open")
+	}
+}
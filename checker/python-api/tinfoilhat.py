@@ -26,8 +26,8 @@ class Checker(object):
 
     def usage(self):
         error("Usage:")
-        error("\tput HOST PORT FLAG\tПоложить флаг в сервис. Возвращает состояние.")
-        error("\tget HOST PORT STATE\tПолучить флаг из сервиса для состояния.")
+        error("\tput HOST PORT FLAG [PLACE]\tПоложить флаг в сервис. Возвращает состояние.")
+        error("\tget HOST PORT STATE [PLACE]\tПолучить флаг из сервиса для состояния.")
         error("\tchk HOST PORT\tПроверить доступность и целостность сервиса.")
 
     def __init__(self, argv):
@@ -41,6 +41,12 @@ class Checker(object):
             host = argv[2]
             port = int(argv[3])
 
+            # Номер хранилища флагов, передаётся только для сервисов
+            # с несколькими хранилищами
+            self.place = 1
+            if cmd in ("put", "get") and len(argv) > 5:
+                self.place = int(argv[5])
+
             if "put" == cmd:
                 if len(argv) < 5:
                     error("Недостаточно аргументов.")
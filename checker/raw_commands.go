@@ -11,25 +11,69 @@
 package checker
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
+	"os/exec"
 	"strings"
+	"syscall"
 	"time"
 
-	system "github.com/jollheef/go-system"
 	"github.com/jollheef/tin_foil_hat/steward"
 )
 
 var (
-	timeout            = "10s" // max checker work time
-	portCheckTimeout   = time.Second * 10
-	connectionAttempts = "2" // ssh option
-	connectTimeout     = "5" // ssh option
+	timeout          = time.Second * 10 // default max checker work time
+	portCheckTimeout = time.Second * 10
 )
 
-// SetTimeout set max checker work time
+const timeoutReason = "Timeout"
+
+// SetTimeout set default max checker work time
 func SetTimeout(d time.Duration) {
 	portCheckTimeout = d
-	timeout = fmt.Sprintf("%ds", int(d.Seconds()))
+	timeout = d
+}
+
+// actionContext returns context with deadline of checker action for service
+func actionContext(ctx context.Context, svc steward.Service,
+	action string) (context.Context, context.CancelFunc) {
+
+	d := timeout
+
+	switch action {
+	case "put":
+		if svc.PutTimeout.Duration != 0 {
+			d = svc.PutTimeout.Duration
+		}
+	case "get":
+		if svc.GetTimeout.Duration != 0 {
+			d = svc.GetTimeout.Duration
+		}
+	case "chk":
+		if svc.CheckTimeout.Duration != 0 {
+			d = svc.CheckTimeout.Duration
+		}
+	}
+
+	return context.WithTimeout(ctx, d)
+}
+
+// timedOut applies result of checker killed by own deadline, cancelled
+// (e.g. by round end) checkers returns error
+func timedOut(ctx context.Context, res *result) (err error) {
+
+	switch ctx.Err() {
+	case context.DeadlineExceeded:
+		res.state = steward.StatusDown
+		res.public = timeoutReason
+	case context.Canceled:
+		res.state = steward.StatusUnknown
+		err = ctx.Err()
+	}
+
+	return
 }
 
 func parseState(ret int) steward.ServiceState {
@@ -39,8 +83,7 @@ func parseState(ret int) steward.ServiceState {
 		return steward.StatusUP
 	case 124: // returns by timeout
 		return steward.StatusDown
-	case 1:
-	case 255: // Could not resolve hostname
+	case 1, 255: // Unhandled exception or could not resolve hostname
 		return steward.StatusError
 	case 2:
 		return steward.StatusMumble
@@ -53,103 +96,221 @@ func parseState(ret int) steward.ServiceState {
 	return steward.StatusUnknown
 }
 
-func put(checker, ip string, port int, flag string) (cred, logs string,
-	state steward.ServiceState, err error) {
+// withPlace append place to checker arguments, zero place means that service
+// has single place, so old checkers works without changes
+func withPlace(args []string, place int) []string {
+	if place == 0 {
+		return args
+	}
+	return append(args, fmt.Sprintf("%d", place))
+}
 
-	cred, logs, ret, err := system.System("timeout", timeout, checker, "put", ip,
-		fmt.Sprintf("%d", port), flag)
+// verdict printed by checker to stdout in json protocol
+type verdict struct {
+	Status  string `json:"status"`  // up, mumble, corrupt, down or error
+	Public  string `json:"public"`  // message visible to team
+	Private string `json:"private"` // logs visible only to organizers
+	FlagID  string `json:"flag_id"` // public id of flag, e.g. user name
+	Cred    string `json:"cred"`    // credentials for get flag
+	Flag    string `json:"flag"`    // flag received from service
+}
 
-	state = parseState(ret)
-	if state != steward.StatusUnknown {
-		err = nil
+// result of checker call
+type result struct {
+	action   string
+	place    int // flag store of put and get, zero for check
+	state    steward.ServiceState
+	ret      int
+	duration time.Duration
+	cred     string
+	flag     string
+	flagID   string
+	public   string
+	logs     string
+}
+
+// status returns status record for result
+func (res result) status(round int, team steward.Team,
+	svc steward.Service) steward.Status {
+
+	return steward.Status{Round: round, TeamID: team.ID,
+		ServiceID: svc.ID, State: res.state, Reason: res.public,
+		Action: res.action, Place: res.place, Duration: res.duration,
+		ExitCode: res.ret, Logs: res.logs}
+}
+
+// parseResult parse checker output, checkers with json protocol print
+// verdict to stdout, other checkers return state by exit code and print
+// credentials (put) or flag (get) to stdout
+func parseResult(action, stdout, stderr string, ret int,
+	jsonProto bool) (res result, err error) {
+
+	res.action = action
+	res.state = parseState(ret)
+	res.ret = ret
+	res.logs = stderr
+
+	output := strings.Trim(stdout, " \n")
+
+	if jsonProto {
+		var v verdict
+		err = json.Unmarshal([]byte(output), &v)
+		if err != nil {
+			return
+		}
+
+		if v.Status != "" {
+			res.state, err = steward.ParseState(v.Status)
+			if err != nil {
+				return
+			}
+		}
+
+		res.cred = v.Cred
+		res.flag = v.Flag
+		res.flagID = v.FlagID
+		res.public = v.Public
+		if v.Private != "" {
+			if res.logs != "" {
+				res.logs += "\n"
+			}
+			res.logs += v.Private
+		}
+		return
 	}
 
-	cred = strings.Trim(cred, " \n")
+	switch action {
+	case "put":
+		res.cred = output
+	case "get":
+		res.flag = output
+	}
 
 	return
 }
 
-func sshPut(host, checker, ip string, port int, flag string) (cred, logs string,
-	state steward.ServiceState, err error) {
+// execute command in own process group, whole group is killed when context
+// is done
+func execute(ctx context.Context, name string, arg ...string) (stdout,
+	stderr string, ret int, err error) {
 
-	cred, logs, ret, err := system.System("ssh",
-		"-o", "ConnectTimeout="+connectTimeout,
-		"-o", "ConnectionAttempts="+connectionAttempts,
-		host, "timeout", timeout, checker,
-		"put", ip, fmt.Sprintf("%d", port), flag)
+	var o, e bytes.Buffer
 
-	state = parseState(ret)
-	if state != steward.StatusUnknown {
-		err = nil
+	cmd := exec.CommandContext(ctx, name, arg...)
+	cmd.Stdout = &o
+	cmd.Stderr = &e
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
 	}
+	cmd.WaitDelay = time.Second
 
-	cred = strings.Trim(cred, " \n")
+	err = cmd.Run()
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		ret = exitErr.ExitCode()
+	} else if err != nil {
+		ret = -1 // checker not started
+	}
 
-	return
+	return o.String(), e.String(), ret, err
 }
 
-func get(checker, ip string, port int, cred string) (flag, logs string,
-	state steward.ServiceState, err error) {
+// run checker action by exec, which returns output and exit code of
+// checker
+func run(ctx context.Context, action string, jsonProto bool,
+	exec func() (stdout, stderr string, ret int, err error)) (res result,
+	err error) {
 
-	flag, logs, ret, err := system.System("timeout", timeout, checker, "get", ip,
-		fmt.Sprintf("%d", port), cred)
+	start := time.Now()
 
-	state = parseState(ret)
-	if state != steward.StatusUnknown {
+	stdout, stderr, ret, err := exec()
+
+	res, perr := parseResult(action, stdout, stderr, ret,
+		jsonProto)
+	res.duration = time.Since(start)
+
+	if ctx.Err() != nil {
+		err = timedOut(ctx, &res)
+		return
+	}
+
+	if _, ok := err.(transportError); ok {
+		// Checker was not started or its result is lost
+		res.state = steward.StatusError
+		res.logs = err.Error()
+		err = nil
+		return
+	}
+
+	if perr != nil {
+		// Broken output of successful checker is checker error
+		if res.state == steward.StatusUP ||
+			res.state == steward.StatusUnknown {
+			res.state = steward.StatusError
+		}
+		res.logs += "\ninvalid checker output: " + perr.Error()
 		err = nil
+		return
 	}
 
-	flag = strings.Trim(flag, " \n")
+	if res.state == steward.StatusUnknown {
+		// Crashed checker is not a fault of service
+		res.state = steward.StatusError
+		res.logs += fmt.Sprintf("\nunexpected exit code %d", ret)
+	}
 
+	err = nil
 	return
 }
 
-func sshGet(host, checker, ip string, port int, cred string) (flag, logs string,
-	state steward.ServiceState, err error) {
+func local(ctx context.Context, checker, action, ip string, port int,
+	jsonProto bool, arg ...string) (res result, err error) {
 
-	flag, logs, ret, err := system.System("ssh",
-		"-o", "ConnectTimeout="+connectTimeout,
-		"-o", "ConnectionAttempts="+connectionAttempts,
-		host, "timeout", timeout, checker,
-		"get", ip, fmt.Sprintf("%d", port), cred)
+	args := append([]string{action, ip, fmt.Sprintf("%d", port)}, arg...)
 
-	state = parseState(ret)
-	if state != steward.StatusUnknown {
-		err = nil
-	}
+	return run(ctx, action, jsonProto, func() (string, string, int, error) {
+		return execute(ctx, checker, args...)
+	})
+}
 
-	flag = strings.Trim(flag, " \n")
+func put(ctx context.Context, checker, ip string, port int, flag string,
+	place int, jsonProto bool) (res result, err error) {
 
-	return
+	return local(ctx, checker, "put", ip, port, jsonProto,
+		withPlace([]string{flag}, place)...)
 }
 
-func check(checker, ip string, port int) (state steward.ServiceState,
-	logs string, err error) {
+func sshPut(ctx context.Context, team steward.Team, checker, ip string,
+	port int, flag string, place int, jsonProto bool) (res result,
+	err error) {
 
-	_, logs, ret, err := system.System("timeout", timeout, checker, "chk", ip,
-		fmt.Sprintf("%d", port))
+	return remote(ctx, team, checker, "put", ip, port, jsonProto,
+		withPlace([]string{flag}, place)...)
+}
 
-	state = parseState(ret)
-	if state != steward.StatusUnknown {
-		err = nil
-	}
+func get(ctx context.Context, checker, ip string, port int, cred string,
+	place int, jsonProto bool) (res result, err error) {
 
-	return
+	return local(ctx, checker, "get", ip, port, jsonProto,
+		withPlace([]string{cred}, place)...)
 }
 
-func sshCheck(host, checker, ip string, port int) (state steward.ServiceState,
-	logs string, err error) {
+func sshGet(ctx context.Context, team steward.Team, checker, ip string,
+	port int, cred string, place int, jsonProto bool) (res result,
+	err error) {
 
-	_, logs, ret, err := system.System("ssh",
-		"-o", "ConnectTimeout="+connectTimeout,
-		"-o", "ConnectionAttempts="+connectionAttempts,
-		host, "timeout", timeout, checker,
-		"chk", ip, fmt.Sprintf("%d", port))
+	return remote(ctx, team, checker, "get", ip, port, jsonProto,
+		withPlace([]string{cred}, place)...)
+}
 
-	state = parseState(ret)
-	if state != steward.StatusUnknown {
-		err = nil
-	}
+func check(ctx context.Context, checker, ip string, port int,
+	jsonProto bool) (res result, err error) {
 
-	return
+	return local(ctx, checker, "chk", ip, port, jsonProto)
+}
+
+func sshCheck(ctx context.Context, team steward.Team, checker, ip string,
+	port int, jsonProto bool) (res result, err error) {
+
+	return remote(ctx, team, checker, "chk", ip, port, jsonProto)
 }
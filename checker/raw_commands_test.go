@@ -0,0 +1,144 @@
+/**
+ * @file raw_commands_test.go
+ * @author Mikhail Klementyev jollheef<AT>riseup.net
+ * @license GNU AGPLv3
+ * @date October, 2026
+ * @brief test checker output parsing
+ */
+
+package checker
+
+import (
+	"context"
+	"io/ioutil"
+	"log"
+	"os"
+	"testing"
+	"time"
+)
+
+import "github.com/jollheef/tin_foil_hat/steward"
+
+func TestParseResult(*testing.T) {
+
+	// Old checkers returns state by exit code
+	res, err := parseResult("put", "cred\n", "logs", 0, false)
+	if err != nil {
+		log.Fatalln("Parse result failed:", err)
+	}
+
+	if res.action != "put" || res.state != steward.StatusUP ||
+		res.ret != 0 || res.cred != "cred" || res.logs != "logs" {
+		log.Fatalln("Invalid result:", res)
+	}
+
+	res, err = parseResult("get", "flag\n", "", 3, false)
+	if err != nil {
+		log.Fatalln("Parse result failed:", err)
+	}
+
+	if res.state != steward.StatusCorrupt || res.ret != 3 ||
+		res.flag != "flag" {
+		log.Fatalln("Invalid result:", res)
+	}
+
+	// Json-like credentials of old checkers are not parsed
+	res, err = parseResult("put", `{"user":"u1","pass":"p1"}`, "", 0,
+		false)
+	if err != nil {
+		log.Fatalln("Parse result failed:", err)
+	}
+
+	if res.state != steward.StatusUP ||
+		res.cred != `{"user":"u1","pass":"p1"}` {
+		log.Fatalln("Invalid result:", res)
+	}
+
+	res, err = parseResult("put", "{u1:p1}", "", 0, false)
+	if err != nil || res.cred != "{u1:p1}" {
+		log.Fatalln("Invalid result:", res, err)
+	}
+
+	// Json protocol
+	res, err = parseResult("put", `{"status": "mumble", `+
+		`"public": "Cannot register", "private": "HTTP 500", `+
+		`"flag_id": "user42", "cred": "user42:pass"}`, "", 0, true)
+	if err != nil {
+		log.Fatalln("Parse result failed:", err)
+	}
+
+	if res.state != steward.StatusMumble || res.public != "Cannot register" ||
+		res.logs != "HTTP 500" || res.flagID != "user42" ||
+		res.cred != "user42:pass" {
+		log.Fatalln("Invalid result:", res)
+	}
+
+	// Exit code used if status is not set
+	res, err = parseResult("get", `{"flag": "flag"}`, "", 2, true)
+	if err != nil {
+		log.Fatalln("Parse result failed:", err)
+	}
+
+	if res.state != steward.StatusMumble || res.flag != "flag" {
+		log.Fatalln("Invalid result:", res)
+	}
+
+	_, err = parseResult("get", `{"status": "great"}`, "", 0, true)
+	if err == nil {
+		log.Fatalln("Invalid status parsed")
+	}
+
+	_, err = parseResult("get", `{"status": `, "", 0, true)
+	if err == nil {
+		log.Fatalln("Invalid json parsed")
+	}
+}
+
+func TestLocalTimeout(*testing.T) {
+
+	script, err := ioutil.TempFile("", "checker")
+	if err != nil {
+		log.Fatalln("Create checker failed:", err)
+	}
+
+	defer os.Remove(script.Name())
+
+	// Child of checker keeps stdout open, so checker output is not
+	// finished until whole process group is killed
+	_, err = script.WriteString("#!/bin/sh\nsleep 10 &\nwait\n")
+	if err != nil {
+		log.Fatalln("Write checker failed:", err)
+	}
+
+	script.Close()
+
+	err = os.Chmod(script.Name(), 0700)
+	if err != nil {
+		log.Fatalln("Chmod checker failed:", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(),
+		100*time.Millisecond)
+	defer cancel()
+
+	res, err := check(ctx, script.Name(), "127.0.0.1", 1, false)
+	if err != nil {
+		log.Fatalln("Check failed:", err)
+	}
+
+	if res.state != steward.StatusDown || res.public != timeoutReason {
+		log.Fatalln("Invalid timeout result:", res)
+	}
+
+	if res.duration > time.Second/2 {
+		log.Fatalln("Process group is not killed:", res.duration)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+
+	_, err = check(ctx, script.Name(), "127.0.0.1", 1, false)
+	if err == nil {
+		log.Fatalln("Cancelled check is not failed")
+	}
+}
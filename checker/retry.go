@@ -0,0 +1,72 @@
+/**
+ * @file retry.go
+ * @author Mikhail Klementyev jollheef<AT>riseup.net
+ * @license GNU AGPLv3
+ * @date October, 2026
+ * @brief retry policy of checks
+ *
+ * Provide function for repeat failed checks, so only consistent failures
+ * are recorded.
+ */
+
+package checker
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jollheef/tin_foil_hat/steward"
+)
+
+// backoff returns delay before attempt after failed attempt (from zero),
+// doubled each time
+func backoff(svc steward.Service, attempt int) time.Duration {
+	return svc.RetryBackoff.Duration << uint(attempt)
+}
+
+// retry call while result is in retry states of service, up to svc.Retries
+// times, logs of failed attempts are kept in result
+func retry(ctx context.Context, team steward.Team, svc steward.Service,
+	call func() (result, error)) (res result, err error) {
+
+	states := svc.RetryStates
+	if states == 0 {
+		states = steward.DefaultRetryStates
+	}
+
+	var logs string
+	var duration time.Duration
+
+	for attempt := 0; ; attempt++ {
+		res, err = call()
+		duration += res.duration
+
+		if err != nil || attempt >= svc.Retries ||
+			!states.Has(res.state) {
+			break
+		}
+
+		log.Printf("Attempt %d, %s, team %s, service %s: %s: %s",
+			attempt+1, res.action, team.Name, svc.Name, res.state,
+			res.logs)
+
+		logs += fmt.Sprintf("attempt %d: %s: %s\n", attempt+1,
+			res.state, res.logs)
+
+		select {
+		case <-time.After(backoff(svc, attempt)):
+			continue
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+
+		break
+	}
+
+	res.duration = duration
+	res.logs = logs + res.logs
+
+	return
+}
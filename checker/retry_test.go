@@ -0,0 +1,89 @@
+/**
+ * @file retry_test.go
+ * @author Mikhail Klementyev jollheef<AT>riseup.net
+ * @license GNU AGPLv3
+ * @date October, 2026
+ * @brief test retry policy
+ */
+
+package checker
+
+import (
+	"context"
+	"log"
+	"strings"
+	"testing"
+	"time"
+)
+
+import "github.com/jollheef/tin_foil_hat/steward"
+
+func TestRetry(*testing.T) {
+
+	ctx := context.Background()
+
+	team := steward.Team{Name: "foo"}
+	svc := steward.Service{Name: "bar", Retries: 2,
+		RetryBackoff: steward.Timeout{Duration: time.Millisecond}}
+
+	var calls int
+	flaky := func() (result, error) {
+		calls++
+		if calls < 3 {
+			return result{action: "chk", state: steward.StatusDown,
+				logs: "timeout", duration: time.Second}, nil
+		}
+		return result{action: "chk", state: steward.StatusUP,
+			duration: time.Second}, nil
+	}
+
+	res, err := retry(ctx, team, svc, flaky)
+	if err != nil {
+		log.Fatalln("Retry failed:", err)
+	}
+
+	if res.state != steward.StatusUP || calls != 3 ||
+		res.duration != 3*time.Second ||
+		strings.Count(res.logs, "attempt") != 2 {
+		log.Fatalln("Invalid result:", res, calls)
+	}
+
+	// Consistent failure recorded after all attempts
+	calls = -10
+	res, _ = retry(ctx, team, svc, flaky)
+	if res.state != steward.StatusDown || calls != -7 {
+		log.Fatalln("Invalid result:", res, calls)
+	}
+
+	// Corrupt is not retried by default
+	calls = 0
+	res, _ = retry(ctx, team, svc, func() (result, error) {
+		calls++
+		return result{state: steward.StatusCorrupt}, nil
+	})
+	if res.state != steward.StatusCorrupt || calls != 1 {
+		log.Fatalln("Corrupt is retried:", calls)
+	}
+
+	svc.RetryStates = steward.NewStateSet(steward.StatusCorrupt)
+	calls = 0
+	retry(ctx, team, svc, func() (result, error) {
+		calls++
+		return result{state: steward.StatusCorrupt}, nil
+	})
+	if calls != 3 {
+		log.Fatalln("Corrupt is not retried:", calls)
+	}
+
+	// Cancelled while backoff
+	svc.RetryBackoff.Duration = time.Hour
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+
+	_, err = retry(cancelled, team, svc, func() (result, error) {
+		return result{state: steward.StatusCorrupt}, nil
+	})
+	if err == nil {
+		log.Fatalln("Cancelled retry is not failed")
+	}
+}
@@ -0,0 +1,379 @@
+/**
+ * @file ssh.go
+ * @author Mikhail Klementyev jollheef<AT>riseup.net
+ * @license GNU AGPLv3
+ * @date October, 2026
+ * @brief ssh transport for run checkers on netbox
+ *
+ * Provide ssh client for run checkers on team netbox. Connections are
+ * reused by all checks until CloseNetboxes called (at the end of round).
+ */
+
+package checker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	"github.com/jollheef/tin_foil_hat/steward"
+)
+
+var (
+	connectionAttempts = 2
+	connectTimeout     = time.Second * 5
+	maxSessions        = 8 // per connection, OpenSSH allows 10 by default
+)
+
+// transportError means that checker result is lost because of netbox
+// connection failure, it's not the same as checker exit code
+type transportError struct {
+	host string
+	err  error
+}
+
+func (e transportError) Error() string {
+	return "netbox " + e.host + ": " + e.err.Error()
+}
+
+// netboxConn is connection to one netbox, locked while dial, sessions
+// limits simultaneous checkers on connection
+type netboxConn struct {
+	sync.Mutex
+	client   *ssh.Client
+	sessions chan struct{}
+}
+
+var netbox = struct {
+	sync.Mutex
+	user       string
+	key        string
+	knownHosts string
+	signers    map[string]ssh.Signer          // { key path : signer }
+	hostKeys   map[string]ssh.HostKeyCallback // { known_hosts path : cb }
+	conns      map[string]*netboxConn         // { user@addr : connection }
+}{
+	signers:  make(map[string]ssh.Signer),
+	hostKeys: make(map[string]ssh.HostKeyCallback),
+	conns:    make(map[string]*netboxConn),
+}
+
+// SetNetbox set default ssh user and private key for netboxes and
+// known_hosts file with netbox host keys, empty knownHosts means
+// ~/.ssh/known_hosts
+func SetNetbox(user, key, knownHosts string) {
+
+	netbox.Lock()
+	defer netbox.Unlock()
+
+	netbox.user = user
+	netbox.key = key
+	netbox.knownHosts = knownHosts
+	netbox.signers = make(map[string]ssh.Signer)
+	netbox.hostKeys = make(map[string]ssh.HostKeyCallback)
+}
+
+// CloseNetboxes close all netbox connections
+func CloseNetboxes() {
+
+	netbox.Lock()
+	conns := netbox.conns
+	netbox.conns = make(map[string]*netboxConn)
+	netbox.Unlock()
+
+	for _, conn := range conns {
+		conn.Lock()
+		if conn.client != nil {
+			conn.client.Close()
+			conn.client = nil
+		}
+		conn.Unlock()
+	}
+}
+
+// netboxAddr returns netbox address with default ssh port
+func netboxAddr(host string) string {
+	if _, _, err := net.SplitHostPort(host); err == nil {
+		return host
+	}
+	return net.JoinHostPort(host, "22")
+}
+
+// netboxConfig returns ssh config for team netbox, team credentials are
+// preferred to defaults
+func netboxConfig(team steward.Team) (config *ssh.ClientConfig, err error) {
+
+	netbox.Lock()
+	defer netbox.Unlock()
+
+	name, key, knownHosts := team.NetboxUser, team.NetboxKey,
+		netbox.knownHosts
+
+	if name == "" {
+		name = netbox.user
+	}
+	if name == "" {
+		var u *user.User
+		u, err = user.Current()
+		if err != nil {
+			return
+		}
+		name = u.Username
+	}
+
+	if key == "" {
+		key = netbox.key
+	}
+	if key == "" {
+		err = fmt.Errorf("no ssh key for netbox of team %s", team.Name)
+		return
+	}
+
+	if knownHosts == "" {
+		var home string
+		home, err = os.UserHomeDir()
+		if err != nil {
+			return
+		}
+		knownHosts = filepath.Join(home, ".ssh", "known_hosts")
+	}
+
+	signer, exist := netbox.signers[key]
+	if !exist {
+		var buf []byte
+		buf, err = ioutil.ReadFile(key)
+		if err != nil {
+			return
+		}
+
+		signer, err = ssh.ParsePrivateKey(buf)
+		if err != nil {
+			return
+		}
+
+		netbox.signers[key] = signer
+	}
+
+	hostKey, exist := netbox.hostKeys[knownHosts]
+	if !exist {
+		hostKey, err = knownhosts.New(knownHosts)
+		if err != nil {
+			return
+		}
+
+		netbox.hostKeys[knownHosts] = hostKey
+	}
+
+	config = &ssh.ClientConfig{
+		User:            name,
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(signer)},
+		HostKeyCallback: hostKey,
+		Timeout:         connectTimeout,
+	}
+
+	return
+}
+
+func dialNetbox(ctx context.Context, addr string,
+	config *ssh.ClientConfig) (client *ssh.Client, err error) {
+
+	dialer := net.Dialer{Timeout: connectTimeout}
+
+	var conn net.Conn
+	for attempt := 0; attempt < connectionAttempts; attempt++ {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+		if err == nil || ctx.Err() != nil {
+			break
+		}
+	}
+	if err != nil {
+		return
+	}
+
+	// Handshake does not use context
+	deadline := time.Now().Add(connectTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetDeadline(deadline)
+
+	c, chans, reqs, err := ssh.NewClientConn(conn, addr, config)
+	if err != nil {
+		conn.Close()
+		return
+	}
+
+	conn.SetDeadline(time.Time{})
+
+	client = ssh.NewClient(c, chans, reqs)
+	return
+}
+
+// netboxClient returns connection to team netbox, new connection is
+// established if there is no one or if it is broken (not replaced yet by
+// other check)
+func netboxClient(ctx context.Context, team steward.Team,
+	broken *ssh.Client) (conn *netboxConn, client *ssh.Client, err error) {
+
+	config, err := netboxConfig(team)
+	if err != nil {
+		return
+	}
+
+	addr := netboxAddr(team.Netbox)
+
+	netbox.Lock()
+	conn, exist := netbox.conns[config.User+"@"+addr]
+	if !exist {
+		conn = &netboxConn{
+			sessions: make(chan struct{}, maxSessions),
+		}
+		netbox.conns[config.User+"@"+addr] = conn
+	}
+	netbox.Unlock()
+
+	conn.Lock()
+	defer conn.Unlock()
+
+	if conn.client != nil && conn.client != broken {
+		client = conn.client
+		return
+	}
+
+	if conn.client != nil {
+		conn.client.Close()
+		conn.client = nil
+	}
+
+	conn.client, err = dialNetbox(ctx, addr, config)
+	client = conn.client
+	return
+}
+
+// alive check connection by keepalive request, new session may be rejected
+// by alive connection (e.g. if MaxSessions of netbox is reached)
+func alive(client *ssh.Client) bool {
+	_, _, err := client.SendRequest("keepalive@openssh.com", true, nil)
+	return err == nil
+}
+
+// shellQuote join arguments to command for remote shell
+func shellQuote(args []string) string {
+
+	quoted := make([]string, 0, len(args))
+	for _, arg := range args {
+		quoted = append(quoted,
+			"'"+strings.Replace(arg, "'", `'\''`, -1)+"'")
+	}
+
+	return strings.Join(quoted, " ")
+}
+
+// sshExecute run command on team netbox, remote command is killed when
+// context is done
+func sshExecute(ctx context.Context, team steward.Team,
+	args []string) (stdout, stderr string, ret int, err error) {
+
+	ret = -1
+
+	conn, client, err := netboxClient(ctx, team, nil)
+	if err != nil {
+		err = transportError{team.Netbox, err}
+		return
+	}
+
+	select {
+	case conn.sessions <- struct{}{}:
+	case <-ctx.Done():
+		err = transportError{team.Netbox, ctx.Err()}
+		return
+	}
+
+	defer func() { <-conn.sessions }()
+
+	session, err := client.NewSession()
+	if err != nil && !alive(client) {
+		// Connection from previous checks is broken, sessions of
+		// other checks on it are failed too
+		_, client, err = netboxClient(ctx, team, client)
+		if err == nil {
+			session, err = client.NewSession()
+		}
+	}
+	if err != nil {
+		err = transportError{team.Netbox, err}
+		return
+	}
+
+	defer session.Close()
+
+	var o, e bytes.Buffer
+	session.Stdout = &o
+	session.Stderr = &e
+
+	done := make(chan error, 1)
+	go func() {
+		done <- session.Run(shellQuote(args))
+	}()
+
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		session.Signal(ssh.SIGKILL)
+		session.Close()
+		err = <-done
+	}
+
+	stdout, stderr = o.String(), e.String()
+
+	switch exitErr := err.(type) {
+	case nil:
+		ret = 0
+	case *ssh.ExitError:
+		ret = exitErr.ExitStatus()
+	default:
+		err = transportError{team.Netbox, err}
+	}
+
+	return
+}
+
+// remoteTimeout returns time left to deadline for timeout utility on remote
+// host
+func remoteTimeout(ctx context.Context) string {
+
+	seconds := 1.0
+
+	if deadline, ok := ctx.Deadline(); ok {
+		seconds = math.Max(math.Ceil(time.Until(deadline).Seconds()), 1)
+	}
+
+	return fmt.Sprintf("%ds", int(seconds))
+}
+
+// remote run checker on team netbox, timeout utility is used on remote
+// side because not all ssh servers support signals
+func remote(ctx context.Context, team steward.Team, checker, action,
+	ip string, port int, jsonProto bool, arg ...string) (res result,
+	err error) {
+
+	args := append([]string{"timeout", remoteTimeout(ctx), checker, action,
+		ip, fmt.Sprintf("%d", port)}, arg...)
+
+	return run(ctx, action, jsonProto, func() (string, string, int,
+		error) {
+		return sshExecute(ctx, team, args)
+	})
+}
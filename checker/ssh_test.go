@@ -0,0 +1,340 @@
+/**
+ * @file ssh_test.go
+ * @author Mikhail Klementyev jollheef<AT>riseup.net
+ * @license GNU AGPLv3
+ * @date October, 2026
+ * @brief test ssh transport
+ */
+
+package checker
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"io/ioutil"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+import "github.com/jollheef/tin_foil_hat/steward"
+
+type testNetbox struct {
+	sync.Mutex
+	listener    net.Listener
+	config      *ssh.ServerConfig
+	connections int
+	commands    []string
+	reject      bool // reject new sessions of alive connection
+	running     int
+	maxRunning  int
+}
+
+func (nb *testNetbox) exec(channel ssh.Channel, command string) {
+
+	nb.Lock()
+	nb.commands = append(nb.commands, command)
+	nb.Unlock()
+
+	var status uint32
+
+	switch {
+	case strings.Contains(command, "'sleep'"):
+		// Wait for kill
+		ioutil.ReadAll(channel)
+		return
+	case strings.Contains(command, "'slow'"):
+		nb.Lock()
+		nb.running++
+		if nb.running > nb.maxRunning {
+			nb.maxRunning = nb.running
+		}
+		nb.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		nb.Lock()
+		nb.running--
+		nb.Unlock()
+	case strings.Contains(command, "'put'"):
+		channel.Write([]byte(`{"status": "up", "cred": "cred"}`))
+	case strings.Contains(command, "'chk'"):
+		channel.Stderr().Write([]byte("logs"))
+		status = 2
+	}
+
+	channel.SendRequest("exit-status", false,
+		ssh.Marshal(struct{ Status uint32 }{status}))
+	channel.Close()
+}
+
+func (nb *testNetbox) serve(conn net.Conn) {
+
+	_, chans, reqs, err := ssh.NewServerConn(conn, nb.config)
+	if err != nil {
+		return
+	}
+
+	nb.Lock()
+	nb.connections++
+	nb.Unlock()
+
+	go ssh.DiscardRequests(reqs)
+
+	for newChannel := range chans {
+		nb.Lock()
+		reject := nb.reject
+		nb.Unlock()
+
+		if reject {
+			newChannel.Reject(ssh.ResourceShortage, "max sessions")
+			continue
+		}
+
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			continue
+		}
+
+		go func() {
+			for req := range requests {
+				if req.Type != "exec" {
+					req.Reply(false, nil)
+					continue
+				}
+
+				var payload struct{ Command string }
+				ssh.Unmarshal(req.Payload, &payload)
+				req.Reply(true, nil)
+
+				go nb.exec(channel, payload.Command)
+			}
+		}()
+	}
+}
+
+func newTestNetbox(clientKey ssh.PublicKey) (nb *testNetbox,
+	hostKey ssh.PublicKey, err error) {
+
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return
+	}
+
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		return
+	}
+
+	nb = &testNetbox{}
+
+	nb.config = &ssh.ServerConfig{
+		PublicKeyCallback: func(meta ssh.ConnMetadata,
+			key ssh.PublicKey) (*ssh.Permissions, error) {
+
+			if meta.User() != "checker" ||
+				!bytes.Equal(key.Marshal(), clientKey.Marshal()) {
+				return nil, os.ErrPermission
+			}
+			return nil, nil
+		},
+	}
+	nb.config.AddHostKey(signer)
+
+	nb.listener, err = net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return
+	}
+
+	go func() {
+		for {
+			conn, err := nb.listener.Accept()
+			if err != nil {
+				return
+			}
+			go nb.serve(conn)
+		}
+	}()
+
+	hostKey = signer.PublicKey()
+	return
+}
+
+func TestRemote(*testing.T) {
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		log.Fatalln("Generate key failed:", err)
+	}
+
+	clientKey, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		log.Fatalln("Public key failed:", err)
+	}
+
+	nb, hostKey, err := newTestNetbox(clientKey)
+	if err != nil {
+		log.Fatalln("Start netbox failed:", err)
+	}
+
+	defer nb.listener.Close()
+
+	addr := nb.listener.Addr().String()
+
+	block, err := ssh.MarshalPrivateKey(priv, "")
+	if err != nil {
+		log.Fatalln("Marshal key failed:", err)
+	}
+
+	keyFile, err := ioutil.TempFile("", "netbox_key")
+	if err != nil {
+		log.Fatalln("Create key failed:", err)
+	}
+
+	defer os.Remove(keyFile.Name())
+
+	keyFile.Write(pem.EncodeToMemory(block))
+	keyFile.Close()
+
+	knownHosts, err := ioutil.TempFile("", "known_hosts")
+	if err != nil {
+		log.Fatalln("Create known hosts failed:", err)
+	}
+
+	defer os.Remove(knownHosts.Name())
+
+	knownHosts.WriteString(knownhosts.Line(
+		[]string{knownhosts.Normalize(addr)}, hostKey) + "\n")
+	knownHosts.Close()
+
+	SetNetbox("checker", keyFile.Name(), knownHosts.Name())
+	defer SetNetbox("", "", "")
+	defer CloseNetboxes()
+
+	ctx, cancel := context.WithTimeout(context.Background(),
+		10*time.Second)
+	defer cancel()
+
+	team := steward.Team{Name: "foo", Netbox: addr}
+
+	res, err := sshPut(ctx, team, "/checker", "10.0.0.2", 80, "flag", 0,
+		true)
+	if err != nil {
+		log.Fatalln("Put failed:", err)
+	}
+
+	if res.state != steward.StatusUP || res.cred != "cred" {
+		log.Fatalln("Invalid put result:", res)
+	}
+
+	if nb.commands[0] != "'timeout' '10s' '/checker' 'put' '10.0.0.2' "+
+		"'80' 'flag'" {
+		log.Fatalln("Invalid command:", nb.commands[0])
+	}
+
+	res, err = sshCheck(ctx, team, "/checker", "10.0.0.2", 80, false)
+	if err != nil {
+		log.Fatalln("Check failed:", err)
+	}
+
+	if res.state != steward.StatusMumble || res.ret != 2 ||
+		res.logs != "logs" {
+		log.Fatalln("Invalid check result:", res)
+	}
+
+	if nb.connections != 1 {
+		log.Fatalln("Connection is not reused:", nb.connections)
+	}
+
+	// Rejected session of alive connection does not reset connection
+	nb.Lock()
+	nb.reject = true
+	nb.Unlock()
+
+	res, err = sshCheck(ctx, team, "/checker", "10.0.0.2", 80, false)
+	if err != nil || res.state != steward.StatusError {
+		log.Fatalln("Invalid result of rejected session:", res, err)
+	}
+
+	nb.Lock()
+	nb.reject = false
+	nb.Unlock()
+
+	if nb.connections != 1 {
+		log.Fatalln("Alive connection is reset:", nb.connections)
+	}
+
+	// Simultaneous checks limited by sessions of connection
+	var wg sync.WaitGroup
+	for i := 0; i < 3*maxSessions; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			res, err := sshCheck(ctx, team, "slow", "10.0.0.2", 80,
+				false)
+			if err != nil || res.state != steward.StatusUP {
+				log.Fatalln("Invalid slow check result:", res,
+					err)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if nb.maxRunning > maxSessions || nb.connections != 1 {
+		log.Fatalln("Sessions limit exceeded:", nb.maxRunning,
+			nb.connections)
+	}
+
+	// Host keys are loaded once
+	os.Remove(knownHosts.Name())
+
+	CloseNetboxes()
+
+	sshCheck(ctx, team, "/checker", "10.0.0.2", 80, false)
+	if nb.connections != 2 {
+		log.Fatalln("Connection is not closed:", nb.connections)
+	}
+
+	// Checker killed by timeout
+	short, cancelShort := context.WithTimeout(ctx, 200*time.Millisecond)
+	defer cancelShort()
+
+	res, err = sshCheck(short, team, "sleep", "10.0.0.2", 80, false)
+	if err != nil {
+		log.Fatalln("Check failed:", err)
+	}
+
+	if res.state != steward.StatusDown || res.public != timeoutReason {
+		log.Fatalln("Invalid timeout result:", res)
+	}
+
+	// Transport failures are checker errors
+	for _, broken := range []steward.Team{
+		{Name: "closed", Netbox: "127.0.0.1:1"},
+		{Name: "unknown host", Netbox: strings.Replace(addr,
+			"127.0.0.1", "localhost", 1)},
+		{Name: "wrong user", Netbox: addr, NetboxUser: "root"},
+	} {
+		res, err = sshCheck(ctx, broken, "/checker", "10.0.0.2", 80,
+			false)
+		if err != nil {
+			log.Fatalln("Check failed:", err)
+		}
+
+		if res.state != steward.StatusError {
+			log.Fatalln("Invalid result for", broken.Name, res)
+		}
+	}
+}
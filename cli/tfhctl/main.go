@@ -45,6 +45,11 @@ var (
 
 	advUnhide   = adv.Command("unhide", "Unhide advisory.")
 	advUnhideID = advUnhide.Arg("id", "advisory id").Required().Int()
+
+	status        = kingpin.Command("status", "View checker results.")
+	statusRound   = status.Arg("round", "round").Required().Int()
+	statusTeam    = status.Arg("team", "team id").Required().Int()
+	statusService = status.Arg("service", "service id").Int()
 )
 
 var (
@@ -109,6 +114,29 @@ func advisoryUnhide(db *sql.DB) {
 	}
 }
 
+func statusList(db *sql.DB) {
+	statuses, err := steward.GetStatuses(db, *statusRound, *statusTeam,
+		*statusService)
+	if err != nil {
+		log.Fatalln("Get statuses fail:", err)
+	}
+
+	for _, s := range statuses {
+
+		fmt.Printf(">>> Round %d, team %d, service %d: %s <<<\n",
+			s.Round, s.TeamID, s.ServiceID, s.State)
+		fmt.Printf("(Action: %s, Exit code: %d, Duration: %s, "+
+			"Timestamp: %s)\n", s.Action, s.ExitCode, s.Duration,
+			s.Timestamp.String())
+
+		if s.Reason != "" {
+			fmt.Println("Reason:", s.Reason)
+		}
+
+		fmt.Println(s.Logs)
+	}
+}
+
 func scoreboardShow(db *sql.DB) {
 	res, err := scoreboard.CollectLastResult(db)
 	if err != nil {
@@ -175,6 +203,9 @@ func main() {
 	case "advisory unhide":
 		advisoryUnhide(db)
 
+	case "status":
+		statusList(db)
+
 	case "scoreboard":
 		scoreboardShow(db)
 	}
@@ -13,6 +13,7 @@ package config
 import (
 	"io/ioutil"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -29,13 +30,17 @@ type Pulse struct {
 	RoundLen     Duration
 	CheckTimeout Duration
 	DarkestTime  Duration
+	FlagLifetime Lifetime
 }
 
 // FlagReceiver config
 type FlagReceiver struct {
-	Addr           string
-	ReceiveTimeout Duration
-	SocketTimeout  Duration
+	Addr               string
+	ReceiveTimeout     Duration
+	SocketTimeout      Duration
+	FlagsPerConnection int
+	HTTPAddr           string
+	MultipleCapture    bool
 }
 
 // AdvisoryReceiver config
@@ -50,7 +55,13 @@ type AdvisoryReceiver struct {
 type Config struct {
 	LogFile        string
 	CheckerTimeout Duration
-	Database       struct {
+	CheckOldFlags  int
+	Scoring        string
+	CheckerWorkers struct {
+		Total      int
+		PerService int
+	}
+	Database struct {
 		Connection     string
 		MaxConnections int
 		SafeReinit     bool
@@ -63,6 +74,24 @@ type Config struct {
 	API struct {
 		AttackBuffer int
 	}
+	Vexillary struct {
+		Scheme  string
+		KeyFile string
+		Format  string
+	}
+	Netbox struct {
+		User       string
+		Key        string
+		KnownHosts string
+	}
+	Agents struct {
+		Addr  string
+		Token string
+	}
+	SLA struct {
+		Up       steward.StateSet
+		Excluded steward.StateSet
+	}
 	Pulse            Pulse
 	FlagReceiver     FlagReceiver
 	AdvisoryReceiver AdvisoryReceiver
@@ -82,6 +111,44 @@ func (d *Duration) UnmarshalTOML(data []byte) (err error) {
 	return
 }
 
+// Lifetime of flag, amount of rounds ("5") or duration ("10m")
+type Lifetime struct {
+	Rounds   int
+	Duration time.Duration
+}
+
+// UnmarshalTOML for Lifetime
+func (l *Lifetime) UnmarshalTOML(data []byte) (err error) {
+
+	*l = Lifetime{}
+
+	raw := strings.Replace(string(data), "\"", "", -1)
+
+	l.Rounds, err = strconv.Atoi(raw)
+	if err == nil {
+		return
+	}
+
+	l.Duration, err = time.ParseDuration(raw)
+	return
+}
+
+// InRounds returns lifetime in rounds with length roundLen, at least one
+func (l Lifetime) InRounds(roundLen time.Duration) (rounds int) {
+
+	rounds = l.Rounds
+
+	if l.Duration != 0 && roundLen != 0 {
+		rounds = int((l.Duration + roundLen - 1) / roundLen)
+	}
+
+	if rounds < 1 {
+		rounds = 1
+	}
+
+	return
+}
+
 // Time type with toml unmarshalling support
 type Time struct {
 	time.Time
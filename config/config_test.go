@@ -9,11 +9,16 @@
 package config_test
 
 import (
+	"fmt"
 	"log"
 	"testing"
+	"time"
 )
 
-import "github.com/jollheef/tin_foil_hat/config"
+import (
+	"github.com/jollheef/tin_foil_hat/config"
+	"github.com/jollheef/tin_foil_hat/steward"
+)
 
 func bug_on_invalid(real, parsed string) {
 	if real != parsed {
@@ -38,5 +43,63 @@ func TestReadConfig(*testing.T) {
 
 	bug_on_invalid("30s", cfg.Pulse.CheckTimeout.String())
 
+	bug_on_invalid("3", fmt.Sprint(cfg.Pulse.FlagLifetime.InRounds(
+		cfg.Pulse.RoundLen.Duration)))
+
+	bug_on_invalid(":8081", cfg.FlagReceiver.HTTPAddr)
+
+	bug_on_invalid("CHANGE_ME_FOO_TOKEN", cfg.Teams[0].Token)
+
+	bug_on_invalid("2", fmt.Sprint(cfg.Services[0].Places))
+
+	bug_on_invalid("true", fmt.Sprint(cfg.Services[0].RetryStates ==
+		steward.DefaultRetryStates))
+
+	bug_on_invalid("false", fmt.Sprint(cfg.Services[0].JSONProtocol))
+
+	bug_on_invalid("true", fmt.Sprint(cfg.Services[1].JSONProtocol))
+
+	bug_on_invalid("4", fmt.Sprint(cfg.Services[1].Workers))
+
+	bug_on_invalid("bar", cfg.Teams[1].NetboxUser)
+
+	bug_on_invalid("checker", cfg.Netbox.User)
+
+	bug_on_invalid("default", cfg.Scoring)
+
+	bug_on_invalid("true", fmt.Sprint(cfg.SLA.Excluded ==
+		steward.NewStateSet(steward.StatusError,
+			steward.StatusUnknown)))
+
+	bug_on_invalid("ping\n", cfg.Services[2].UDPProbe)
+
+	bug_on_invalid("3s", cfg.Services[2].UDPProbeTimeout.String())
+
 	// other values has built-in types
 }
+
+func TestLifetime(*testing.T) {
+
+	var l config.Lifetime
+
+	err := l.UnmarshalTOML([]byte("4"))
+	if err != nil {
+		log.Fatalln("Unmarshal lifetime failed:", err)
+	}
+
+	bug_on_invalid("4", fmt.Sprint(l.InRounds(time.Minute)))
+
+	err = l.UnmarshalTOML([]byte("\"90s\""))
+	if err != nil {
+		log.Fatalln("Unmarshal lifetime failed:", err)
+	}
+
+	bug_on_invalid("2", fmt.Sprint(l.InRounds(time.Minute)))
+
+	bug_on_invalid("1", fmt.Sprint(config.Lifetime{}.InRounds(time.Minute)))
+
+	err = l.UnmarshalTOML([]byte("\"forever\""))
+	if err == nil {
+		log.Fatalln("Invalid lifetime parsed")
+	}
+}
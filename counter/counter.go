@@ -16,12 +16,49 @@ import (
 	"github.com/jollheef/tin_foil_hat/steward"
 )
 
-// CountStatesResult count round states (up/down/etc.) result
-func CountStatesResult(db *sql.DB, round, team int,
+var (
+	upStates       = steward.NewStateSet(steward.StatusUP)
+	excludedStates steward.StateSet
+)
+
+// SetSLAStates set states counted as up and states excluded from SLA
+// (e.g. checker errors), other states are counted as down
+func SetSLAStates(up, excluded steward.StateSet) {
+
+	if up == 0 {
+		up = steward.NewStateSet(steward.StatusUP)
+	}
+
+	upStates = up
+	excludedStates = excluded
+}
+
+// Available returns true if service in state does not block its team (e.g.
+// submit of flags): state counted as up, excluded from SLA or checker error,
+// checker faults never penalise teams
+func Available(state steward.ServiceState) bool {
+	return upStates.Has(state) || excludedStates.Has(state) ||
+		state == steward.StatusError
+}
+
+// CountStatesResult count round states (up/down/etc.) result, round of
+// service with all states excluded or voided is counted as up
+func CountStatesResult(db steward.Queryer, round, team int,
 	service steward.Service) (score float64, err error) {
 
-	halfStatus := steward.Status{round, team, service.ID,
-		steward.StatusUnknown}
+	// Voided round is up even if checker has not put any status
+	void, err := steward.IsVoidRound(db, round, service.ID)
+	if err != nil {
+		return
+	}
+
+	if void {
+		score = 1
+		return
+	}
+
+	halfStatus := steward.Status{Round: round, TeamID: team,
+		ServiceID: service.ID, State: steward.StatusUnknown}
 
 	states, err := steward.GetStates(db, halfStatus)
 	if err != nil {
@@ -32,20 +69,31 @@ func CountStatesResult(db *sql.DB, round, team int,
 		return
 	}
 
-	ok := 0.0
+	ok, counted := 0.0, 0.0
 	for _, state := range states {
-		if state == steward.StatusUP {
+		if excludedStates.Has(state) {
+			continue
+		}
+
+		counted++
+
+		if upStates.Has(state) {
 			ok++
 		}
 	}
 
-	score = 1.0 / float64(len(states)) * ok
+	if counted == 0 {
+		score = 1
+		return
+	}
+
+	score = 1.0 / counted * ok
 
 	return
 }
 
 // CountDefenceResult count round defence result
-func CountDefenceResult(db *sql.DB, round, team int,
+func CountDefenceResult(db steward.Queryer, round, team int,
 	services []steward.Service) (defence float64, err error) {
 
 	defence = 0
@@ -65,61 +113,135 @@ func CountDefenceResult(db *sql.DB, round, team int,
 	return
 }
 
-// CountRound count round result
-func CountRound(db *sql.DB, round int, teams []steward.Team,
-	services []steward.Service) (err error) {
+// flagCost returns cost of flag, service cost shared between its places
+func flagCost(services []steward.Service, flag steward.Flag) float64 {
 
-	roundRes := make(map[steward.Team]steward.RoundResult)
+	perService := 1.0 / float64(len(services))
 
-	for _, team := range teams {
+	for _, svc := range services {
+		if svc.ID == flag.ServiceID && svc.Places > 1 {
+			return perService / float64(svc.Places)
+		}
+	}
 
-		res := steward.RoundResult{TeamID: team.ID, Round: round}
+	return perService
+}
 
-		def, err := CountDefenceResult(db, round, team.ID, services)
-		if err != nil {
-			return err
+// collectRound collect SLA and captures of round
+func collectRound(db steward.Queryer, round int, teams []steward.Team,
+	services []steward.Service) (r Round, err error) {
+
+	r = Round{ID: round, Teams: teams, Services: services,
+		SLA:    make(map[int]map[int]float64),
+		Scores: make(map[int]float64)}
+
+	for _, team := range teams {
+
+		// At first round no result exist
+		var last steward.RoundResult
+		last, err = steward.GetLastResult(db, team.ID)
+		if err == sql.ErrNoRows {
+			last, err = steward.RoundResult{}, nil
+		} else if err != nil {
+			return
 		}
 
-		res.DefenceScore = def * 2
+		r.Scores[team.ID] = last.AttackScore + last.DefenceScore
 
-		roundRes[team] = res
+		r.SLA[team.ID] = make(map[int]float64)
+
+		for _, svc := range services {
+			r.SLA[team.ID][svc.ID], err = CountStatesResult(db,
+				round, team.ID, svc)
+			if err != nil {
+				return
+			}
+		}
 	}
 
-	perService := 1.0 / float64(len(services))
+	captures, err := steward.GetRoundCaptures(db, round)
+	if err != nil {
+		return
+	}
 
+	attackers := make(map[int]bool)
 	for _, team := range teams {
+		attackers[team.ID] = true
+	}
 
-		cflags, err := steward.GetCapturedFlags(db, round, team.ID)
-		if err != nil {
-			return err
+	capturers := make(map[int]int) // { flag_id : teams_captured_flag }
+
+	// Captures are ordered by time, so same round capturers of flag are
+	// counted in order of capture
+	for _, capture := range captures {
+
+		if !attackers[capture.TeamID] {
+			continue
 		}
 
-		for _, flag := range cflags {
+		flag := capture.Flag
+
+		c := Capture{Flag: flag, Attacker: capture.TeamID}
+
+		// Every capturing team get points, but victim lose points
+		// only once per flag, in round of first capture
+		if _, counted := capturers[flag.ID]; !counted {
 
-			attackedTeam, err := steward.GetTeam(db, flag.TeamID)
+			var first int
+			first, err = steward.FirstCaptureRound(db, flag.ID)
 			if err != nil {
-				return err
+				return
 			}
 
-			res := roundRes[attackedTeam]
-			res.DefenceScore -= perService
-			if res.DefenceScore < 0 {
-				res.DefenceScore = 0
-			}
-			roundRes[attackedTeam] = res
+			c.First = first == round
 
-			attackRes := roundRes[team]
-			attackRes.AttackScore += perService
-			roundRes[team] = attackRes
+			capturers[flag.ID], err = steward.CountCaptures(db,
+				flag.ID, round-1)
+			if err != nil {
+				return
+			}
 		}
+
+		capturers[flag.ID]++
+		c.Capturers = capturers[flag.ID]
+
+		r.Captures = append(r.Captures, c)
+	}
+
+	return
+}
+
+// CountRound count round result by selected scoring
+func CountRound(db *sql.DB, round int, teams []steward.Team,
+	services []steward.Service) (err error) {
+
+	return countRound(db, scoring, round, teams, services)
+}
+
+func countRound(db steward.Queryer, s Scoring, round int,
+	teams []steward.Team, services []steward.Service) (err error) {
+
+	r, err := collectRound(db, round, teams, services)
+	if err != nil {
+		return
 	}
 
-	for _, res := range roundRes {
-		_, err := steward.AddRoundResult(db, res)
+	res := s.Count(r)
+
+	for _, team := range teams {
+
+		_, err = steward.AddRoundResult(db, res.Teams[team.ID])
 		if err != nil {
-			return err
+			return
 		}
 
+		for _, svc := range services {
+			_, err = steward.AddServiceResult(db,
+				res.Services[team.ID][svc.ID])
+			if err != nil {
+				return
+			}
+		}
 	}
 
 	return
@@ -49,6 +49,8 @@ func (t testDB) Close() {
 	t.db.Exec("DROP TABLE status")
 	t.db.Exec("DROP TABLE round")
 	t.db.Exec("DROP TABLE round_result")
+	t.db.Exec("DROP TABLE service_result")
+	t.db.Exec("DROP TABLE void_round")
 
 	t.db.Close()
 }
@@ -91,6 +93,71 @@ func TestCountStatesResult(*testing.T) {
 
 }
 
+func TestCountStatesResultExcluded(*testing.T) {
+
+	db, err := openDB()
+	if err != nil {
+		log.Fatalln("Open database failed:", err)
+	}
+
+	defer db.Close()
+
+	defer counter.SetSLAStates(0, 0)
+
+	r, t := 1, 1 // round, team id
+
+	svc := steward.Service{ID: 1, Name: "foo", Port: 8080}
+
+	for _, state := range []steward.ServiceState{steward.StatusUP,
+		steward.StatusUP, steward.StatusError, steward.StatusDown} {
+
+		steward.PutStatus(db.db, steward.Status{Round: r, TeamID: t,
+			ServiceID: svc.ID, State: state})
+	}
+
+	check := func(must_be float64) {
+		res, err := counter.CountStatesResult(db.db, r, t, svc)
+		if err != nil {
+			log.Fatalln("Count states failed:", err)
+		}
+
+		if res != must_be {
+			log.Fatalln("Result invalid:", res, "instead", must_be)
+		}
+	}
+
+	check(0.5)
+
+	counter.SetSLAStates(0, steward.NewStateSet(steward.StatusError))
+	check(2.0 / 3.0)
+
+	counter.SetSLAStates(steward.NewStateSet(steward.StatusUP,
+		steward.StatusDown), steward.NewStateSet(steward.StatusError))
+	check(1)
+
+	// Voided round is not counted
+	counter.SetSLAStates(0, 0)
+
+	err = steward.VoidRound(db.db, r, svc.ID)
+	if err != nil {
+		log.Fatalln("Void round failed:", err)
+	}
+
+	check(1)
+
+	// Voided round without statuses is up too
+	r++
+
+	check(0)
+
+	err = steward.VoidRound(db.db, r, svc.ID)
+	if err != nil {
+		log.Fatalln("Void round failed:", err)
+	}
+
+	check(1)
+}
+
 func TestCountDefenceResult(*testing.T) {
 
 	db, err := openDB()
@@ -273,6 +340,12 @@ func TestCountRound(*testing.T) {
 		log.Fatalln("Capture flag failed:", err)
 	}
 
+	// Same flag captured by several teams
+	err = steward.CaptureFlag(db.db, flag1.ID, teams[1].ID)
+	if err != nil {
+		log.Fatalln("Capture flag failed:", err)
+	}
+
 	err = counter.CountRound(db.db, round, teams, services)
 	if err != nil {
 		log.Fatalln("Count round failed:", err)
@@ -284,7 +357,7 @@ func TestCountRound(*testing.T) {
 	}
 
 	res, err = steward.GetRoundResult(db.db, teams[1].ID, round)
-	if err != nil || res.AttackScore != 0.0 || res.DefenceScore != 1.75 {
+	if err != nil || res.AttackScore != 0.25 || res.DefenceScore != 1.75 {
 		log.Fatalln("Invalid result:", res)
 	}
 
@@ -299,3 +372,198 @@ func TestCountRound(*testing.T) {
 	}
 
 }
+
+func TestCountRoundCapturers(*testing.T) {
+
+	db, err := openDB()
+	if err != nil {
+		log.Fatalln("Open database failed:", err)
+	}
+
+	defer db.Close()
+
+	fillTestTeams(db.db)
+
+	fillTestServices(db.db)
+
+	round, err := steward.NewRound(db.db, time.Minute)
+	if err != nil {
+		log.Fatalln("Create new round failed:", err)
+	}
+
+	teams, err := steward.GetTeams(db.db)
+	if err != nil {
+		log.Fatalln("Get teams failed:", err)
+	}
+
+	services, err := steward.GetServices(db.db)
+	if err != nil {
+		log.Fatalln("Get services failed:", err)
+	}
+
+	flg := steward.Flag{ID: -1, Flag: "flag", Round: round,
+		TeamID: teams[0].ID, ServiceID: services[0].ID, Place: 1}
+
+	err = steward.AddFlag(db.db, flg)
+	if err != nil {
+		log.Fatalln("Add flag to database failed:", err)
+	}
+
+	flg, err = steward.GetFlagInfo(db.db, flg.Flag)
+	if err != nil {
+		log.Fatalln("Get flag info failed:", err)
+	}
+
+	// Last team in list captures flag first in same round
+	for _, team := range []steward.Team{teams[2], teams[1]} {
+		err = steward.CaptureFlag(db.db, flg.ID, team.ID)
+		if err != nil {
+			log.Fatalln("Capture flag failed:", err)
+		}
+	}
+
+	counter.SetScoring("faust")
+	defer counter.SetScoring("default")
+
+	err = counter.CountRound(db.db, round, teams, services)
+	if err != nil {
+		log.Fatalln("Count round failed:", err)
+	}
+
+	first, err := steward.GetRoundResult(db.db, teams[2].ID, round)
+	if err != nil {
+		log.Fatalln("Get round result failed:", err)
+	}
+
+	second, err := steward.GetRoundResult(db.db, teams[1].ID, round)
+	if err != nil {
+		log.Fatalln("Get round result failed:", err)
+	}
+
+	// First capturer get 1 + 1/1, second one 1 + 1/2
+	if first.AttackScore != 2 || second.AttackScore != 1.5 {
+		log.Fatalln("Invalid attack of capturers:", first, second)
+	}
+
+	// Dynamic flag value is shared in order of capture too, flag of
+	// equal team costs 1/len(services), round is recounted after its end
+	_, err = steward.NewRound(db.db, time.Minute)
+	if err != nil {
+		log.Fatalln("Create new round failed:", err)
+	}
+
+	_, err = counter.Recount(db.db, counter.Dynamic{}, false)
+	if err != nil {
+		log.Fatalln("Recount failed:", err)
+	}
+
+	first, err = steward.GetRoundResult(db.db, teams[2].ID, round)
+	if err != nil {
+		log.Fatalln("Get round result failed:", err)
+	}
+
+	second, err = steward.GetRoundResult(db.db, teams[1].ID, round)
+	if err != nil {
+		log.Fatalln("Get round result failed:", err)
+	}
+
+	if first.AttackScore != 0.25 || second.AttackScore != 0.125 {
+		log.Fatalln("Invalid dynamic attack of capturers:", first,
+			second)
+	}
+}
+
+func TestCountRoundPlaces(*testing.T) {
+
+	db, err := openDB()
+	if err != nil {
+		log.Fatalln("Open database failed:", err)
+	}
+
+	defer db.Close()
+
+	fillTestTeams(db.db)
+
+	err = steward.AddService(db.db, steward.Service{ID: -1, Name: "Foo",
+		Port: 8080, Places: 2})
+	if err != nil {
+		log.Fatalln("Add service failed:", err)
+	}
+
+	err = steward.AddService(db.db, steward.Service{ID: -1, Name: "Bar",
+		Port: 8081})
+	if err != nil {
+		log.Fatalln("Add service failed:", err)
+	}
+
+	round, err := steward.NewRound(db.db, time.Minute)
+	if err != nil {
+		log.Fatalln("Create new round failed:", err)
+	}
+
+	teams, err := steward.GetTeams(db.db)
+	if err != nil {
+		log.Fatalln("Get teams failed:", err)
+	}
+
+	services, err := steward.GetServices(db.db)
+	if err != nil {
+		log.Fatalln("Get services failed:", err)
+	}
+
+	for _, team := range teams {
+		for _, svc := range services {
+			err = steward.PutStatus(db.db, steward.Status{
+				Round: round, TeamID: team.ID,
+				ServiceID: svc.ID, State: steward.StatusUP})
+			if err != nil {
+				log.Fatalln("Put status to database failed:", err)
+			}
+		}
+	}
+
+	// Flags of first team: both places of Foo and single place of Bar
+	flags := []steward.Flag{
+		{Flag: "foo1", ServiceID: services[0].ID, Place: 1},
+		{Flag: "foo2", ServiceID: services[0].ID, Place: 2},
+		{Flag: "bar1", ServiceID: services[1].ID, Place: 1},
+	}
+
+	for _, flg := range flags {
+		flg.ID = -1
+		flg.Round = round
+		flg.TeamID = teams[0].ID
+
+		err = steward.AddFlag(db.db, flg)
+		if err != nil {
+			log.Fatalln("Add flag to database failed:", err)
+		}
+	}
+
+	for _, flag := range []string{"foo1", "bar1"} {
+		flg, err := steward.GetFlagInfo(db.db, flag)
+		if err != nil {
+			log.Fatalln("Get flag info failed:", err)
+		}
+
+		err = steward.CaptureFlag(db.db, flg.ID, teams[1].ID)
+		if err != nil {
+			log.Fatalln("Capture flag failed:", err)
+		}
+	}
+
+	err = counter.CountRound(db.db, round, teams, services)
+	if err != nil {
+		log.Fatalln("Count round failed:", err)
+	}
+
+	res, err := steward.GetRoundResult(db.db, teams[0].ID, round)
+	if err != nil || res.AttackScore != 0.0 || res.DefenceScore != 1.25 {
+		log.Fatalln("Invalid result:", res)
+	}
+
+	res, err = steward.GetRoundResult(db.db, teams[1].ID, round)
+	if err != nil || res.AttackScore != 0.75 || res.DefenceScore != 2.0 {
+		log.Fatalln("Invalid result:", res)
+	}
+}
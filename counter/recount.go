@@ -0,0 +1,116 @@
+/**
+ * @file recount.go
+ * @author Mikhail Klementyev jollheef<AT>riseup.net
+ * @license GNU AGPLv3
+ * @date October, 2026
+ * @brief full recount of round results
+ *
+ * Contain function for recount results of all rounds from statuses and
+ * captured flags, e.g. after manual correction of statuses.
+ */
+
+package counter
+
+import (
+	"database/sql"
+
+	"github.com/jollheef/tin_foil_hat/steward"
+)
+
+// Diff of stored and recounted total result of team
+type Diff struct {
+	Team      steward.Team
+	Stored    steward.RoundResult
+	Recounted steward.RoundResult
+}
+
+// Changed returns true if recounted result is not same as stored
+func (d Diff) Changed() bool {
+	return d.Stored.AttackScore != d.Recounted.AttackScore ||
+		d.Stored.DefenceScore != d.Recounted.DefenceScore
+}
+
+func lastResults(db steward.Queryer, teams []steward.Team) (
+	results map[int]steward.RoundResult, err error) {
+
+	results = make(map[int]steward.RoundResult)
+
+	for _, team := range teams {
+
+		res, err := steward.GetLastResult(db, team.ID)
+		if err == sql.ErrNoRows {
+			res = steward.RoundResult{TeamID: team.ID}
+		} else if err != nil {
+			return results, err
+		}
+
+		results[team.ID] = res
+	}
+
+	return
+}
+
+// Recount remove all round results and count every finished round (also
+// rounds without results, e.g. if count failed) by scoring s in one
+// transaction, returns diff of total results for each team. Results are not
+// changed if dryRun is true. Must not be used while game is running.
+func Recount(db *sql.DB, s Scoring, dryRun bool) (diffs []Diff,
+	err error) {
+
+	teams, err := steward.GetTeams(db)
+	if err != nil {
+		return
+	}
+
+	services, err := steward.GetServices(db)
+	if err != nil {
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return
+	}
+
+	defer func() {
+		if err != nil || dryRun {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	last, err := steward.GetLastFinishedRound(tx)
+	if err != nil {
+		return
+	}
+
+	stored, err := lastResults(tx, teams)
+	if err != nil {
+		return
+	}
+
+	err = steward.DeleteRoundResults(tx)
+	if err != nil {
+		return
+	}
+
+	for round := 1; round <= last; round++ {
+		err = countRound(tx, s, round, teams, services)
+		if err != nil {
+			return
+		}
+	}
+
+	recounted, err := lastResults(tx, teams)
+	if err != nil {
+		return
+	}
+
+	for _, team := range teams {
+		diffs = append(diffs, Diff{Team: team,
+			Stored: stored[team.ID], Recounted: recounted[team.ID]})
+	}
+
+	return
+}
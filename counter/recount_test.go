@@ -0,0 +1,205 @@
+/**
+ * @file recount_test.go
+ * @author Mikhail Klementyev jollheef<AT>riseup.net
+ * @license GNU AGPLv3
+ * @date October, 2026
+ * @brief test full recount of round results
+ */
+
+package counter_test
+
+import (
+	"log"
+	"testing"
+	"time"
+)
+
+import (
+	"github.com/jollheef/tin_foil_hat/counter"
+	"github.com/jollheef/tin_foil_hat/steward"
+)
+
+func TestRecount(*testing.T) {
+
+	db, err := openDB()
+	if err != nil {
+		log.Fatalln("Open database failed:", err)
+	}
+
+	defer db.Close()
+
+	fillTestTeams(db.db)
+
+	fillTestServices(db.db)
+
+	teams, err := steward.GetTeams(db.db)
+	if err != nil {
+		log.Fatalln("Get teams failed:", err)
+	}
+
+	services, err := steward.GetServices(db.db)
+	if err != nil {
+		log.Fatalln("Get services failed:", err)
+	}
+
+	for i := 0; i < 2; i++ {
+
+		round, err := steward.NewRound(db.db, time.Minute)
+		if err != nil {
+			log.Fatalln("Create new round failed:", err)
+		}
+
+		for _, team := range teams {
+			for _, svc := range services {
+				err = steward.PutStatus(db.db, steward.Status{
+					Round: round, TeamID: team.ID,
+					ServiceID: svc.ID, State: steward.StatusUP})
+				if err != nil {
+					log.Fatalln("Put status failed:", err)
+				}
+			}
+		}
+
+		err = counter.CountRound(db.db, round, teams, services)
+		if err != nil {
+			log.Fatalln("Count round failed:", err)
+		}
+	}
+
+	// Next round is started, so last counted round is finished
+	_, err = steward.NewRound(db.db, time.Minute)
+	if err != nil {
+		log.Fatalln("Create new round failed:", err)
+	}
+
+	// Manual correction of status in first round
+	err = steward.PutStatus(db.db, steward.Status{Round: 1,
+		TeamID: teams[0].ID, ServiceID: services[0].ID,
+		State: steward.StatusDown})
+	if err != nil {
+		log.Fatalln("Put status failed:", err)
+	}
+
+	// Dry run returns same diffs as recount, but results are not changed
+	for _, dryRun := range []bool{true, false} {
+		checkRecount(db, teams, dryRun)
+	}
+
+	res, err := steward.GetRoundResult(db.db, teams[0].ID, 1)
+	if err != nil || res.DefenceScore != 1.75 {
+		log.Fatalln("Invalid result of first round:", res)
+	}
+
+	srs, err := steward.GetLastServiceResults(db.db, teams[0].ID)
+	if err != nil {
+		log.Fatalln("Get last service results failed:", err)
+	}
+
+	if len(srs) != len(services) {
+		log.Fatalln("Invalid amount of service results:", len(srs))
+	}
+
+	for _, sr := range srs {
+
+		// Mean SLA of two rounds
+		sla, defence := 1.0, 1.0
+		if sr.ServiceID == services[0].ID {
+			sla, defence = 0.75, 0.75
+		}
+
+		if sr.SLA != sla || sr.DefenceScore != defence {
+			log.Fatalln("Invalid service result:", sr)
+		}
+	}
+}
+
+func checkRecount(db testDB, teams []steward.Team, dryRun bool) {
+
+	diffs, err := counter.Recount(db.db, counter.Default{}, dryRun)
+	if err != nil {
+		log.Fatalln("Recount failed:", err)
+	}
+
+	if len(diffs) != len(teams) {
+		log.Fatalln("Invalid amount of diffs:", len(diffs))
+	}
+
+	for _, diff := range diffs {
+
+		if diff.Stored.DefenceScore != 4.0 {
+			log.Fatalln("Invalid stored result:", diff.Stored)
+		}
+
+		if diff.Team.ID != teams[0].ID {
+			if diff.Changed() {
+				log.Fatalln("Result changed:", diff)
+			}
+			continue
+		}
+
+		if !diff.Changed() || diff.Recounted.DefenceScore != 3.75 {
+			log.Fatalln("Invalid recounted result:", diff.Recounted)
+		}
+	}
+
+	if !dryRun {
+		return
+	}
+
+	res, err := steward.GetRoundResult(db.db, teams[0].ID, 1)
+	if err != nil || res.DefenceScore != 2.0 {
+		log.Fatalln("Result changed by dry run:", res)
+	}
+}
+
+func TestRecountMissingRound(*testing.T) {
+
+	db, err := openDB()
+	if err != nil {
+		log.Fatalln("Open database failed:", err)
+	}
+
+	defer db.Close()
+
+	fillTestTeams(db.db)
+
+	fillTestServices(db.db)
+
+	teams, err := steward.GetTeams(db.db)
+	if err != nil {
+		log.Fatalln("Get teams failed:", err)
+	}
+
+	services, err := steward.GetServices(db.db)
+	if err != nil {
+		log.Fatalln("Get services failed:", err)
+	}
+
+	// Count of second round is failed, third round is current
+	for round := 1; round <= 3; round++ {
+
+		_, err = steward.NewRound(db.db, time.Minute)
+		if err != nil {
+			log.Fatalln("Create new round failed:", err)
+		}
+
+		if round != 1 {
+			continue
+		}
+
+		err = counter.CountRound(db.db, round, teams, services)
+		if err != nil {
+			log.Fatalln("Count round failed:", err)
+		}
+	}
+
+	_, err = counter.Recount(db.db, counter.Default{}, false)
+	if err != nil {
+		log.Fatalln("Recount failed:", err)
+	}
+
+	last, err := steward.GetLastResult(db.db, teams[0].ID)
+	if err != nil || last.Round != 2 {
+		log.Fatalln("Missing round is not recounted:", last, err)
+	}
+}
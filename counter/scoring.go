@@ -0,0 +1,310 @@
+/**
+ * @file scoring.go
+ * @author Mikhail Klementyev jollheef<AT>riseup.net
+ * @license GNU AGPLv3
+ * @date October, 2026
+ * @brief scoring formulas
+ *
+ * Contain interface of scoring formula and formulas selectable from config:
+ * default, RuCTF-style (flag points multiplied by SLA), FAUST-style
+ * (offense, defense and SLA) and dynamic (flag value depends on victim
+ * strength and amount of capturers).
+ */
+
+package counter
+
+import (
+	"errors"
+	"math"
+
+	"github.com/jollheef/tin_foil_hat/steward"
+)
+
+// Capture of flag in round
+type Capture struct {
+	Flag      steward.Flag
+	Attacker  int  // team id
+	First     bool // first capture of flag, victim lose points only once
+	Capturers int  // amount of teams captured flag up to this capture
+}
+
+// Round contains all data needed to count round results
+type Round struct {
+	ID       int
+	Teams    []steward.Team
+	Services []steward.Service
+	SLA      map[int]map[int]float64 // { team_id : { service_id : sla } }
+	Scores   map[int]float64         // { team_id : score before round }
+	Captures []Capture
+}
+
+// Result of round by teams and by services of teams, team result is sum of
+// its service results
+type Result struct {
+	// { team_id : result }
+	Teams map[int]steward.RoundResult
+	// { team_id : { service_id : result } }
+	Services map[int]map[int]steward.ServiceResult
+}
+
+// Scoring formula, returns attack and defence points of teams gained in
+// round (results are accumulated between rounds)
+type Scoring interface {
+	Count(round Round) (res Result)
+}
+
+var scorings = map[string]Scoring{
+	"default": Default{},
+	"ructf":   RuCTF{},
+	"faust":   FAUST{},
+	"dynamic": Dynamic{},
+}
+
+var scoring Scoring = Default{}
+
+// GetScoring returns scoring formula by name ("default", "ructf", "faust"
+// or "dynamic"), empty name means default
+func GetScoring(name string) (s Scoring, err error) {
+
+	if name == "" {
+		name = "default"
+	}
+
+	s, exist := scorings[name]
+	if !exist {
+		err = errors.New("unknown scoring '" + name + "'")
+	}
+
+	return
+}
+
+// SetScoring select scoring formula used by CountRound
+func SetScoring(name string) (err error) {
+
+	s, err := GetScoring(name)
+	if err != nil {
+		return
+	}
+
+	scoring = s
+	return
+}
+
+func newResult(round Round) (res Result) {
+
+	res.Teams = make(map[int]steward.RoundResult)
+	res.Services = make(map[int]map[int]steward.ServiceResult)
+
+	for _, team := range round.Teams {
+
+		res.Teams[team.ID] = steward.RoundResult{TeamID: team.ID,
+			Round: round.ID}
+
+		res.Services[team.ID] = make(map[int]steward.ServiceResult)
+
+		for _, svc := range round.Services {
+			res.Services[team.ID][svc.ID] = steward.ServiceResult{
+				TeamID: team.ID, ServiceID: svc.ID,
+				Round: round.ID,
+				SLA:   round.SLA[team.ID][svc.ID]}
+		}
+	}
+
+	return
+}
+
+// add points to service result of team
+func (res Result) add(team, service int, attack, defence float64) {
+
+	sr := res.Services[team][service]
+	sr.AttackScore += attack
+	sr.DefenceScore += defence
+	res.Services[team][service] = sr
+}
+
+// sum service results to team results, negative defence of services is
+// cleared before if clamp is set
+func (res Result) sum(round Round, clamp bool) {
+
+	for _, team := range round.Teams {
+
+		tr := res.Teams[team.ID]
+
+		for _, svc := range round.Services {
+			sr := res.Services[team.ID][svc.ID]
+
+			if clamp && sr.DefenceScore < 0 {
+				sr.DefenceScore = 0
+				res.Services[team.ID][svc.ID] = sr
+			}
+
+			tr.AttackScore += sr.AttackScore
+			tr.DefenceScore += sr.DefenceScore
+		}
+
+		res.Teams[team.ID] = tr
+	}
+}
+
+// Default scoring: defence is doubled mean SLA, each capture costs
+// 1/len(services) (shared between places of service), victim lose cost of
+// flag once
+type Default struct{}
+
+// Count round results
+func (Default) Count(round Round) (res Result) {
+
+	res = newResult(round)
+
+	perService := 1.0 / float64(len(round.Services))
+
+	for _, team := range round.Teams {
+		for _, svc := range round.Services {
+			res.add(team.ID, svc.ID, 0,
+				round.SLA[team.ID][svc.ID]*perService*2)
+		}
+	}
+
+	for _, c := range round.Captures {
+
+		cost := flagCost(round.Services, c.Flag)
+
+		if c.First {
+			res.add(c.Flag.TeamID, c.Flag.ServiceID, 0, -cost)
+		}
+
+		res.add(c.Attacker, c.Flag.ServiceID, cost, 0)
+	}
+
+	res.sum(round, true)
+	return
+}
+
+// RuCTF scoring: flag points multiplied by SLA of service, so points of
+// service which is down are lost. Attacker gets cost of flag multiplied by
+// SLA of own service, defence is mean SLA minus lost flags multiplied by
+// SLA of victim service.
+type RuCTF struct{}
+
+// Count round results
+func (RuCTF) Count(round Round) (res Result) {
+
+	res = newResult(round)
+
+	perService := 1.0 / float64(len(round.Services))
+
+	for _, team := range round.Teams {
+		for _, svc := range round.Services {
+			res.add(team.ID, svc.ID, 0,
+				round.SLA[team.ID][svc.ID]*perService)
+		}
+	}
+
+	for _, c := range round.Captures {
+
+		cost := flagCost(round.Services, c.Flag)
+
+		if c.First {
+			res.add(c.Flag.TeamID, c.Flag.ServiceID, 0, -cost*
+				round.SLA[c.Flag.TeamID][c.Flag.ServiceID])
+		}
+
+		res.add(c.Attacker, c.Flag.ServiceID,
+			cost*round.SLA[c.Attacker][c.Flag.ServiceID], 0)
+	}
+
+	res.sum(round, true)
+	return
+}
+
+// FAUST scoring: offense is 1 + 1/capturers for each captured flag, victim
+// lose capturers^0.75 for each flag (counted incrementally as flag is
+// captured by more teams), SLA is sum of SLA of services multiplied by
+// sqrt(len(teams)). Defence is SLA and defense together.
+type FAUST struct{}
+
+// Count round results
+func (FAUST) Count(round Round) (res Result) {
+
+	res = newResult(round)
+
+	for _, team := range round.Teams {
+		for _, svc := range round.Services {
+			res.add(team.ID, svc.ID, 0, round.SLA[team.ID][svc.ID]*
+				math.Sqrt(float64(len(round.Teams))))
+		}
+	}
+
+	for _, c := range round.Captures {
+
+		res.add(c.Flag.TeamID, c.Flag.ServiceID, 0,
+			-(math.Pow(float64(c.Capturers), 0.75) -
+				math.Pow(float64(c.Capturers-1), 0.75)))
+
+		res.add(c.Attacker, c.Flag.ServiceID,
+			1+1/float64(c.Capturers), 0)
+	}
+
+	res.sum(round, false)
+	return
+}
+
+// victimWeight returns weight of team by its score before round, from
+// 2/(n+1) for weakest team to 2n/(n+1) for strongest one, 1 in average
+func victimWeight(round Round, team int) float64 {
+
+	var position float64 // amount of weaker teams, equal counts as half
+
+	for _, t := range round.Teams {
+
+		if t.ID == team {
+			continue
+		}
+
+		if round.Scores[t.ID] < round.Scores[team] {
+			position++
+		} else if round.Scores[t.ID] == round.Scores[team] {
+			position += 0.5
+		}
+	}
+
+	return 2 * (position + 1) / float64(len(round.Teams)+1)
+}
+
+// Dynamic scoring: same as default, but flag value is multiplied by weight
+// of victim (by attack and defence before round) and divided by amount of
+// teams captured flag before (in order of capture, also within round), so
+// farming of weakest team is worth less than attack of top team
+type Dynamic struct{}
+
+// Count round results
+func (Dynamic) Count(round Round) (res Result) {
+
+	res = newResult(round)
+
+	perService := 1.0 / float64(len(round.Services))
+
+	for _, team := range round.Teams {
+		for _, svc := range round.Services {
+			res.add(team.ID, svc.ID, 0,
+				round.SLA[team.ID][svc.ID]*perService*2)
+		}
+	}
+
+	for _, c := range round.Captures {
+
+		cost := flagCost(round.Services, c.Flag)
+
+		if c.First {
+			res.add(c.Flag.TeamID, c.Flag.ServiceID, 0, -cost)
+		}
+
+		value := cost * victimWeight(round, c.Flag.TeamID) /
+			float64(c.Capturers)
+
+		res.add(c.Attacker, c.Flag.ServiceID, value, 0)
+	}
+
+	res.sum(round, true)
+	return
+}
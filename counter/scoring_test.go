@@ -0,0 +1,148 @@
+/**
+ * @file scoring_test.go
+ * @author Mikhail Klementyev jollheef<AT>riseup.net
+ * @license GNU AGPLv3
+ * @date October, 2026
+ * @brief test scoring formulas
+ */
+
+package counter_test
+
+import (
+	"log"
+	"math"
+	"testing"
+)
+
+import (
+	"github.com/jollheef/tin_foil_hat/counter"
+	"github.com/jollheef/tin_foil_hat/steward"
+)
+
+func testRound() counter.Round {
+
+	teams := []steward.Team{{ID: 1, Name: "foo"}, {ID: 2, Name: "bar"}}
+
+	services := []steward.Service{{ID: 1, Name: "foo"},
+		{ID: 2, Name: "bar"}}
+
+	sla := map[int]map[int]float64{
+		1: {1: 1, 2: 1},
+		2: {1: 1, 2: 0.5},
+	}
+
+	flag := steward.Flag{ID: 1, Flag: "f", Round: 1, TeamID: 2,
+		ServiceID: 1}
+
+	captures := []counter.Capture{{Flag: flag, Attacker: 1, First: true,
+		Capturers: 1}}
+
+	return counter.Round{ID: 1, Teams: teams, Services: services,
+		SLA: sla, Captures: captures}
+}
+
+func checkScore(name string, res steward.RoundResult, attack,
+	defence float64) {
+
+	if math.Abs(res.AttackScore-attack) > 0.0001 ||
+		math.Abs(res.DefenceScore-defence) > 0.0001 {
+		log.Fatalf("%s: team %d: attack %f defence %f instead %f %f",
+			name, res.TeamID, res.AttackScore, res.DefenceScore,
+			attack, defence)
+	}
+}
+
+func TestScoring(*testing.T) {
+
+	r := testRound()
+
+	res := counter.Default{}.Count(r)
+	checkScore("default", res.Teams[1], 0.5, 2)
+	checkScore("default", res.Teams[2], 0, 1)
+
+	// Victim lose points of attacked service only
+	svc := res.Services[2][1]
+	if svc.DefenceScore != 0.5 || svc.SLA != 1 {
+		log.Fatalln("Invalid service result:", svc)
+	}
+
+	svc = res.Services[2][2]
+	if svc.DefenceScore != 0.5 || svc.SLA != 0.5 {
+		log.Fatalln("Invalid service result:", svc)
+	}
+
+	svc = res.Services[1][1]
+	if svc.AttackScore != 0.5 || res.Services[1][2].AttackScore != 0 {
+		log.Fatalln("Invalid service result:", svc)
+	}
+
+	// Negative defence of service is cleared, so team result is sum of
+	// service results
+	r.SLA[2][1] = 0
+
+	res = counter.Default{}.Count(r)
+	checkScore("default", res.Teams[2], 0, 0.5)
+
+	if res.Services[2][1].DefenceScore != 0 {
+		log.Fatalln("Negative defence of service:", res.Services[2][1])
+	}
+
+	r.SLA[2][1] = 1
+
+	res = counter.RuCTF{}.Count(r)
+	checkScore("ructf", res.Teams[1], 0.5, 1)
+	checkScore("ructf", res.Teams[2], 0, 0.25)
+
+	res = counter.FAUST{}.Count(r)
+	checkScore("faust", res.Teams[1], 2, 2*math.Sqrt2)
+	checkScore("faust", res.Teams[2], 0, 1.5*math.Sqrt2-1)
+
+	// Second capturer of flag gets less, victim lose less
+	r.Captures = append(r.Captures, counter.Capture{Flag: r.Captures[0].Flag,
+		Attacker: 1, Capturers: 2})
+
+	res = counter.FAUST{}.Count(r)
+	checkScore("faust", res.Teams[1], 3.5, 2*math.Sqrt2)
+	checkScore("faust", res.Teams[2], 0, 1.5*math.Sqrt2-math.Pow(2, 0.75))
+
+	for _, name := range []string{"", "default", "ructf", "faust",
+		"dynamic"} {
+		err := counter.SetScoring(name)
+		if err != nil {
+			log.Fatalln("Select scoring", name, "fail:", err)
+		}
+	}
+
+	err := counter.SetScoring("unknown")
+	if err == nil {
+		log.Fatalln("Unknown scoring selected")
+	}
+
+	counter.SetScoring("default")
+}
+
+func TestDynamicScoring(*testing.T) {
+
+	r := testRound()
+
+	// Equal teams, flag costs same as in default scoring
+	res := counter.Dynamic{}.Count(r)
+	checkScore("dynamic", res.Teams[1], 0.5, 2)
+	checkScore("dynamic", res.Teams[2], 0, 1)
+
+	// Flag of weakest team costs less
+	r.Scores = map[int]float64{1: 10, 2: 0}
+
+	res = counter.Dynamic{}.Count(r)
+	checkScore("dynamic", res.Teams[1], 0.5*2/3, 2)
+	checkScore("dynamic", res.Teams[2], 0, 1)
+
+	// Flag of strongest team costs more, shared between capturers
+	r.Scores = map[int]float64{1: 0, 2: 10}
+	r.Captures = append(r.Captures, counter.Capture{Flag: r.Captures[0].Flag,
+		Attacker: 1, Capturers: 2})
+
+	res = counter.Dynamic{}.Count(r)
+	checkScore("dynamic", res.Teams[1], 0.5*4/3*(1+0.5), 2)
+	checkScore("dynamic", res.Teams[2], 0, 1)
+}
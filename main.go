@@ -11,15 +11,19 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
 	"os"
+	"regexp"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/jollheef/tin_foil_hat/checker"
 	"github.com/jollheef/tin_foil_hat/config"
+	"github.com/jollheef/tin_foil_hat/counter"
 	"github.com/jollheef/tin_foil_hat/pulse"
 	"github.com/jollheef/tin_foil_hat/receiver"
 	"github.com/jollheef/tin_foil_hat/scoreboard"
@@ -33,6 +37,22 @@ var (
 		"Path to configuration file.").String()
 
 	dbReinit = kingpin.Flag("reinit", "Reinit database.").Bool()
+
+	agentURL = kingpin.Flag("agent",
+		"Run as checker agent of tin_foil_hat at url.").String()
+
+	recount = kingpin.Flag("recount",
+		"Recount results of all rounds and exit.").Bool()
+
+	recountScoring = kingpin.Flag("recount-scoring",
+		"Scoring for recount, same as in config by default.").String()
+
+	recountDryRun = kingpin.Flag("recount-dry-run",
+		"Show diffs of recount, results are not changed.").Bool()
+
+	voidRounds = kingpin.Flag("void",
+		"Void round of service in SLA and recount, "+
+			"e.g. 42:web.").Strings()
 )
 
 var (
@@ -97,12 +117,72 @@ func reinitDatabase(db *sql.DB, config config.Config) {
 	}
 }
 
+func voidRound(db *sql.DB, arg string) {
+
+	var round int
+	var name string
+
+	_, err := fmt.Sscanf(strings.Replace(arg, ":", " ", 1), "%d %s",
+		&round, &name)
+	if err != nil {
+		log.Fatalln("Invalid round of service", arg, "(e.g. 42:web)")
+	}
+
+	services, err := steward.GetServices(db)
+	if err != nil {
+		log.Fatalln("Get services fail:", err)
+	}
+
+	for _, svc := range services {
+		if svc.Name == name {
+			err = steward.VoidRound(db, round, svc.ID)
+			if err != nil {
+				log.Fatalln("Void round fail:", err)
+			}
+			return
+		}
+	}
+
+	log.Fatalln("Unknown service", name)
+}
+
+func recountResults(db *sql.DB, name string, dryRun bool) {
+
+	s, err := counter.GetScoring(name)
+	if err != nil {
+		log.Fatalln("Select scoring fail:", err)
+	}
+
+	diffs, err := counter.Recount(db, s, dryRun)
+	if err != nil {
+		log.Fatalln("Recount fail:", err)
+	}
+
+	for _, diff := range diffs {
+
+		mark := " "
+		if diff.Changed() {
+			mark = "*"
+		}
+
+		fmt.Printf("%s %-20s attack %8.2f -> %8.2f, "+
+			"defence %8.2f -> %8.2f\n", mark, diff.Team.Name,
+			diff.Stored.AttackScore, diff.Recounted.AttackScore,
+			diff.Stored.DefenceScore, diff.Recounted.DefenceScore)
+	}
+}
+
 func main() {
 
 	fmt.Println(buildInfo())
 
 	kingpin.Parse()
 
+	// Void is not undone by rollback of dry run
+	if *recountDryRun && len(*voidRounds) != 0 {
+		log.Fatalln("--void cannot be used with --recount-dry-run")
+	}
+
 	if *configPath == "" {
 		log.Println("Use default config path")
 		*configPath = "/etc/tinfoilhat/tinfoilhat.toml"
@@ -113,6 +193,19 @@ func main() {
 		log.Fatalln("Cannot open config:", err)
 	}
 
+	for _, svc := range config.Services {
+		if svc.Checker != "" && !checker.Registered(svc.Checker) {
+			log.Fatalf("Checker %s of service %s is not registered",
+				svc.Checker, svc.Name)
+		}
+
+		_, err = regexp.Compile(svc.UDPProbeResponse)
+		if err != nil {
+			log.Fatalf("Invalid udp probe response of service %s: %s",
+				svc.Name, err)
+		}
+	}
+
 	logFile, err := os.OpenFile(config.LogFile,
 		os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
 	if err != nil {
@@ -131,6 +224,21 @@ func main() {
 
 	log.Println("RLIMIT_NOFILE CUR:", rlim.Cur, "MAX:", rlim.Max)
 
+	if *agentURL != "" {
+		checker.SetNetbox(config.Netbox.User, config.Netbox.Key,
+			config.Netbox.KnownHosts)
+		checker.RunAgent(context.Background(), *agentURL,
+			config.Agents.Token, config.CheckerWorkers.Total)
+		return
+	}
+
+	err = counter.SetScoring(config.Scoring)
+	if err != nil {
+		log.Fatalln("Select scoring fail:", err)
+	}
+
+	counter.SetSLAStates(config.SLA.Up, config.SLA.Excluded)
+
 	db, err := steward.OpenDatabase(config.Database.Connection)
 	if err != nil {
 		log.Fatalln("Open database fail:", err)
@@ -144,24 +252,71 @@ func main() {
 		reinitDatabase(db, config)
 	}
 
+	for _, arg := range *voidRounds {
+		voidRound(db, arg)
+	}
+
+	if *recount || *recountDryRun || len(*voidRounds) != 0 {
+		if *recountScoring == "" {
+			*recountScoring = config.Scoring
+		}
+		recountResults(db, *recountScoring, *recountDryRun)
+		return
+	}
+
 	checker.SetTimeout(config.CheckerTimeout.Duration)
+	checker.SetOldFlags(config.CheckOldFlags)
+	checker.SetWorkers(config.CheckerWorkers.Total,
+		config.CheckerWorkers.PerService)
+	checker.SetNetbox(config.Netbox.User, config.Netbox.Key,
+		config.Netbox.KnownHosts)
+
+	if config.Agents.Addr != "" {
+		err = checker.ServeAgents(config.Agents.Addr,
+			config.Agents.Token)
+		if err != nil {
+			log.Fatalln("Start checker agent server fail:", err)
+		}
+	}
 
 	if config.AdvisoryReceiver.Disabled {
 		scoreboard.DisableAdvisory()
 	}
 
-	priv, err := vexillary.GenerateKey()
+	scheme, err := vexillary.LoadScheme(config.Vexillary.Scheme,
+		config.Vexillary.KeyFile, config.Vexillary.Format)
 	if err != nil {
-		log.Fatalln("Generate key fail:", err)
+		log.Fatalln("Load flag scheme fail:", err)
 	}
 
 	attackFlow := make(chan scoreboard.Attack, config.API.AttackBuffer)
 
-	go receiver.FlagReceiver(db, priv, config.FlagReceiver.Addr,
+	receiver.SetMultipleCapture(config.FlagReceiver.MultipleCapture)
+
+	flagLifetime := config.Pulse.FlagLifetime.InRounds(
+		config.Pulse.RoundLen.Duration)
+
+	receiver.SetFlagLifetime(flagLifetime)
+	checker.SetFlagLifetime(flagLifetime)
+
+	if config.FlagReceiver.FlagsPerConnection == 0 {
+		config.FlagReceiver.FlagsPerConnection = 1
+	}
+
+	go receiver.FlagReceiver(db, scheme, config.FlagReceiver.Addr,
 		config.FlagReceiver.ReceiveTimeout.Duration,
 		config.FlagReceiver.SocketTimeout.Duration,
+		config.FlagReceiver.FlagsPerConnection,
 		attackFlow)
 
+	if config.FlagReceiver.HTTPAddr != "" {
+		go receiver.FlagHTTPReceiver(db, scheme,
+			config.FlagReceiver.HTTPAddr,
+			config.FlagReceiver.ReceiveTimeout.Duration,
+			config.FlagReceiver.FlagsPerConnection,
+			attackFlow)
+	}
+
 	go receiver.AdvisoryReceiver(db, config.AdvisoryReceiver.Addr,
 		config.AdvisoryReceiver.ReceiveTimeout.Duration,
 		config.AdvisoryReceiver.SocketTimeout.Duration)
@@ -175,7 +330,7 @@ func main() {
 		config.Pulse.Lunch.Duration,
 		config.Pulse.DarkestTime.Duration)
 
-	err = pulse.Pulse(db, priv,
+	err = pulse.Pulse(db, scheme,
 		config.Pulse.Start.Time,
 		config.Pulse.Half.Duration,
 		config.Pulse.Lunch.Duration,
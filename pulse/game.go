@@ -9,7 +9,7 @@
 package pulse
 
 import (
-	"crypto/rsa"
+	"context"
 	"database/sql"
 	"log"
 	"math/rand"
@@ -19,6 +19,7 @@ import (
 	"github.com/jollheef/tin_foil_hat/checker"
 	"github.com/jollheef/tin_foil_hat/counter"
 	"github.com/jollheef/tin_foil_hat/steward"
+	"github.com/jollheef/tin_foil_hat/vexillary"
 )
 
 // RandomizeTimeout Do not forget something like rand.Seed(time.Now().UnixNano())
@@ -48,7 +49,7 @@ func RandomizeTimeout(duration, deviation time.Duration) (ret time.Duration) {
 // Game contains game info
 type Game struct {
 	db       *sql.DB
-	priv     *rsa.PrivateKey
+	scheme   vexillary.Scheme
 	roundLen time.Duration
 	timeout  time.Duration
 	teams    []steward.Team
@@ -56,10 +57,10 @@ type Game struct {
 }
 
 // NewGame create new Game object
-func NewGame(db *sql.DB, priv *rsa.PrivateKey, roundLen time.Duration,
+func NewGame(db *sql.DB, scheme vexillary.Scheme, roundLen time.Duration,
 	timeout time.Duration) (g Game, err error) {
 
-	g.priv = priv
+	g.scheme = scheme
 	g.roundLen = roundLen
 	g.timeout = timeout
 	g.db = db
@@ -117,23 +118,35 @@ func (g Game) Round(counters *sync.WaitGroup) (err error) {
 
 	log.Println("New round", roundNo)
 
-	err = checker.PutFlags(g.db, g.priv, roundNo, g.teams, g.services)
+	round, err := steward.CurrentRound(g.db)
 	if err != nil {
 		return
 	}
 
-	round, err := steward.CurrentRound(g.db)
+	roundEnd := round.StartTime.Add(round.Len)
+
+	// Checkers still running at the end of round are killed, cancel (not
+	// deadline) used to distinguish round end from checker timeout
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	timer := time.AfterFunc(time.Until(roundEnd), cancel)
+	defer timer.Stop()
+
+	defer checker.CloseNetboxes()
+
+	err = checker.PutFlags(ctx, g.db, g.scheme, roundNo, g.teams,
+		g.services)
 	if err != nil {
 		return
 	}
 
-	roundEnd := round.StartTime.Add(round.Len)
-
 	for time.Now().Before(roundEnd) {
 
 		log.Println("Round", round.ID, "check start")
 
-		err = checker.CheckFlags(g.db, round.ID, g.teams, g.services)
+		err = checker.CheckFlags(ctx, g.db, round.ID, g.teams,
+			g.services)
 		if err != nil {
 			return
 		}
@@ -51,6 +51,8 @@ func (t testDB) Close() {
 	t.db.Exec("DROP TABLE status")
 	t.db.Exec("DROP TABLE round")
 	t.db.Exec("DROP TABLE round_result")
+	t.db.Exec("DROP TABLE service_result")
+	t.db.Exec("DROP TABLE void_round")
 
 	t.db.Close()
 }
@@ -120,9 +122,14 @@ func TestGame(*testing.T) {
 
 	defer svc.Stop()
 
-	priv, err := vexillary.GenerateKey()
+	secret, err := vexillary.GenerateSecret()
 	if err != nil {
-		log.Fatalln("Generate key fail:", err)
+		log.Fatalln("Generate secret fail:", err)
+	}
+
+	scheme, err := vexillary.NewHMAC(secret, vexillary.DefaultFormat)
+	if err != nil {
+		log.Fatalln("Create flag scheme fail:", err)
 	}
 
 	for index, team := range []string{"FooTeam", "BarTeam", "BazTeam"} {
@@ -156,7 +163,7 @@ func TestGame(*testing.T) {
 	round_len := 30 * time.Second
 	timeout_between_check := 10 * time.Second
 
-	game, err := pulse.NewGame(db.db, priv, round_len, timeout_between_check)
+	game, err := pulse.NewGame(db.db, scheme, round_len, timeout_between_check)
 
 	defer game.Over()
 
@@ -11,10 +11,11 @@
 package pulse
 
 import (
-	"crypto/rsa"
 	"database/sql"
 	"log"
 	"time"
+
+	"github.com/jollheef/tin_foil_hat/vexillary"
 )
 
 // Wait for time
@@ -32,7 +33,7 @@ func Wait(end time.Time, timeout time.Duration) (waited bool) {
 }
 
 // Pulse manage game
-func Pulse(db *sql.DB, priv *rsa.PrivateKey, startTime time.Time,
+func Pulse(db *sql.DB, scheme vexillary.Scheme, startTime time.Time,
 	half, lunch, roundLen, checkTimeout time.Duration) (err error) {
 
 	log.Println("Launching pulse...")
@@ -45,7 +46,7 @@ func Pulse(db *sql.DB, priv *rsa.PrivateKey, startTime time.Time,
 
 	log.Println("Contest start time", startTime)
 
-	game, err := NewGame(db, priv, roundLen, checkTimeout)
+	game, err := NewGame(db, scheme, roundLen, checkTimeout)
 
 	defer game.Over()
 
@@ -0,0 +1,162 @@
+/**
+ * @file http.go
+ * @author Mikhail Klementyev jollheef<AT>riseup.net
+ * @license GNU AGPLv3
+ * @date October, 2026
+ * @brief http api for receive flags from commands
+ *
+ * Provide http server for receive flags. Team send json array of flags with
+ * token in X-Team-Token header and get json array of verdicts.
+ */
+
+package receiver
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+import (
+	"github.com/jollheef/tin_foil_hat/scoreboard"
+	"github.com/jollheef/tin_foil_hat/steward"
+	"github.com/jollheef/tin_foil_hat/vexillary"
+)
+
+const (
+	tokenHeader = "X-Team-Token"
+	maxBodySize = 1 << 20
+)
+
+// Verdict for flag submitted by http api
+type Verdict struct {
+	Flag     string `json:"flag"`
+	Accepted bool   `json:"accepted"`
+	Msg      string `json:"msg"`
+}
+
+type flagHandler struct {
+	db         *sql.DB
+	scheme     vexillary.Scheme
+	timeout    time.Duration
+	flagsLimit int
+	attackFlow chan scoreboard.Attack
+
+	mutex    sync.Mutex
+	requests map[int]time.Time // { team_id : last_request_time }
+}
+
+func httpError(w http.ResponseWriter, msg string, code int) {
+	http.Error(w, strings.Trim(msg, "\n"), code)
+}
+
+func (h *flagHandler) tooFast(teamID int) bool {
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if time.Now().Before(h.requests[teamID].Add(h.timeout)) {
+		return true
+	}
+
+	h.requests[teamID] = time.Now()
+	return false
+}
+
+func (h *flagHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != http.MethodPut && r.Method != http.MethodPost {
+		w.Header().Set("Allow", "PUT, POST")
+		httpError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	team, err := steward.GetTeamByToken(h.db, r.Header.Get(tokenHeader))
+	if err == sql.ErrNoRows {
+		log.Println("\tInvalid token from", r.RemoteAddr)
+		httpError(w, invalidTeamMsg, http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		log.Println("\tGet team by token failed:", err)
+		httpError(w, internalErrorMsg, http.StatusInternalServerError)
+		return
+	}
+
+	if h.tooFast(team.ID) {
+		log.Println("\tToo fast requests by", team.Name)
+		httpError(w, attemptsLimitMsg, http.StatusTooManyRequests)
+		return
+	}
+
+	var flags []string
+
+	err = json.NewDecoder(http.MaxBytesReader(w, r.Body,
+		maxBodySize)).Decode(&flags)
+	if err != nil {
+		httpError(w, "Body must be json array of flags",
+			http.StatusBadRequest)
+		return
+	}
+
+	if len(flags) > h.flagsLimit {
+		httpError(w, flagsLimitMsg, http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	verdicts := make([]Verdict, 0, len(flags))
+
+	for _, flag := range flags {
+		msg := receiveFlag(h.db, h.scheme, team, flag, h.attackFlow)
+
+		verdicts = append(verdicts, Verdict{
+			Flag:     flag,
+			Accepted: msg == capturedMsg,
+			Msg:      strings.Trim(msg, "\n"),
+		})
+	}
+
+	buf, err := json.Marshal(verdicts)
+	if err != nil {
+		log.Println("Serialization error:", err)
+		httpError(w, internalErrorMsg, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	_, err = w.Write(buf)
+	if err != nil {
+		log.Println("Verdicts write error:", err)
+	}
+}
+
+// FlagHTTPReceiver starts http flag receiver, each request can contain up
+// to flagsLimit flags, team can send one request per timeout
+func FlagHTTPReceiver(db *sql.DB, scheme vexillary.Scheme, addr string,
+	timeout time.Duration, flagsLimit int,
+	attackFlow chan scoreboard.Attack) {
+
+	log.Println("Launching http receiver at", addr, "...")
+
+	h := &flagHandler{
+		db:         db,
+		scheme:     scheme,
+		timeout:    timeout,
+		flagsLimit: flagsLimit,
+		attackFlow: attackFlow,
+		requests:   make(map[int]time.Time),
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/flags", h)
+
+	err := http.ListenAndServe(addr, mux)
+	if err != nil {
+		log.Println("Http receiver error:", err)
+	}
+}
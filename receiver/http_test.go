@@ -0,0 +1,169 @@
+/**
+ * @file http_test.go
+ * @author Mikhail Klementyev jollheef<AT>riseup.net
+ * @license GNU AGPLv3
+ * @date October, 2026
+ * @brief test http flag receiver
+ */
+
+package receiver
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+import (
+	"github.com/jollheef/tin_foil_hat/scoreboard"
+	"github.com/jollheef/tin_foil_hat/steward"
+	"github.com/jollheef/tin_foil_hat/vexillary"
+)
+
+func putFlags(h http.Handler, token string, flags []string) (
+	code int, verdicts []Verdict) {
+
+	buf, _ := json.Marshal(flags)
+
+	r := httptest.NewRequest("PUT", "/flags", bytes.NewReader(buf))
+	r.Header.Set(tokenHeader, token)
+
+	w := httptest.NewRecorder()
+
+	h.ServeHTTP(w, r)
+
+	if w.Code == http.StatusOK {
+		err := json.Unmarshal(w.Body.Bytes(), &verdicts)
+		if err != nil {
+			log.Fatalln("Invalid response:", err)
+		}
+	}
+
+	return w.Code, verdicts
+}
+
+func TestFlagHandler(*testing.T) {
+
+	db, err := openDB()
+	if err != nil {
+		log.Fatalln("Open database failed:", err)
+	}
+
+	defer db.Close()
+
+	secret, err := vexillary.GenerateSecret()
+	if err != nil {
+		log.Fatalln("Generate secret failed:", err)
+	}
+
+	scheme, err := vexillary.NewHMAC(secret, vexillary.DefaultFormat)
+	if err != nil {
+		log.Fatalln("Create flag scheme failed:", err)
+	}
+
+	roundID, err := steward.NewRound(db.db, time.Minute)
+	if err != nil {
+		log.Fatalln("New round failed:", err)
+	}
+
+	rounds.invalidate()
+
+	// Subnet does not matter for http receiver
+	t := steward.Team{ID: -1, Name: "TestTeam", Subnet: "10.0.0.0/24",
+		Vulnbox: "1", Token: "secret"}
+
+	teamID, err := steward.AddTeam(db.db, t)
+	if err != nil {
+		log.Fatalln("Add team failed:", err)
+	}
+
+	teams.invalidate()
+
+	serviceID := 1
+
+	steward.PutStatus(db.db, steward.Status{Round: roundID, TeamID: teamID,
+		ServiceID: serviceID, State: steward.StatusUP})
+
+	flag, err := scheme.GenerateFlag()
+	if err != nil {
+		log.Fatalln("Generate flag failed:", err)
+	}
+
+	err = steward.AddFlag(db.db, steward.Flag{ID: -1, Flag: flag,
+		Round: roundID, TeamID: teamID + 1, ServiceID: serviceID})
+	if err != nil {
+		log.Fatalln("Add flag failed:", err)
+	}
+
+	h := &flagHandler{
+		db:         db.db,
+		scheme:     scheme,
+		timeout:    time.Nanosecond,
+		flagsLimit: 3,
+		attackFlow: make(chan scoreboard.Attack, 10),
+		requests:   make(map[int]time.Time),
+	}
+
+	code, _ := putFlags(h, "invalid", []string{flag})
+	if code != http.StatusUnauthorized {
+		log.Fatalln("Invalid token accepted, code", code)
+	}
+
+	code, _ = putFlags(h, "secret", []string{"1", "2", "3", "4"})
+	if code != http.StatusRequestEntityTooLarge {
+		log.Fatalln("Flags limit not applied, code", code)
+	}
+
+	code, verdicts := putFlags(h, "secret", []string{flag, flag, "1"})
+	if code != http.StatusOK {
+		log.Fatalln("Valid request rejected, code", code)
+	}
+
+	mustBe := []Verdict{
+		{flag, true, "Captured!"},
+		{flag, false, "Flag already captured by you"},
+		{"1", false, "Invalid flag"},
+	}
+
+	if len(verdicts) != len(mustBe) {
+		log.Fatalln("Verdicts", verdicts, "instead", mustBe)
+	}
+
+	for i := range mustBe {
+		if verdicts[i] != mustBe[i] {
+			log.Fatalln("Verdict", verdicts[i], "instead", mustBe[i])
+		}
+	}
+
+	// Flag captured by another team
+	t2 := steward.Team{ID: -1, Name: "TestTeam2", Subnet: "10.0.1.0/24",
+		Vulnbox: "2", Token: "secret2"}
+
+	team2ID, err := steward.AddTeam(db.db, t2)
+	if err != nil {
+		log.Fatalln("Add team failed:", err)
+	}
+
+	teams.invalidate()
+
+	steward.PutStatus(db.db, steward.Status{Round: roundID, TeamID: team2ID,
+		ServiceID: serviceID, State: steward.StatusUP})
+
+	_, verdicts = putFlags(h, "secret2", []string{flag})
+	if len(verdicts) != 1 || verdicts[0].Msg != "Flag already captured" {
+		log.Fatalln("Invalid verdicts", verdicts)
+	}
+
+	// Flag can be captured by every team in multiple capture mode
+	SetMultipleCapture(true)
+	defer SetMultipleCapture(false)
+
+	_, verdicts = putFlags(h, "secret2", []string{flag})
+	if len(verdicts) != 1 || !verdicts[0].Accepted {
+		log.Fatalln("Invalid verdicts", verdicts)
+	}
+}
@@ -5,18 +5,17 @@
  * @date September, 2015
  * @brief routine for receive flags from commands
  *
- * Provide tcp server for receive flags. After receive flag daemon perform
- * validate flag, check flag round and write result to db.
+ * Provide tcp server for receive flags, one flag per line. After receive flag
+ * daemon perform validate flag, check flag round and write result to db.
  */
 
 package receiver
 
 import (
 	"bufio"
-	"crypto/rsa"
 	"database/sql"
-	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net"
 	"strings"
@@ -24,6 +23,7 @@ import (
 )
 
 import (
+	"github.com/jollheef/tin_foil_hat/counter"
 	"github.com/jollheef/tin_foil_hat/scoreboard"
 	"github.com/jollheef/tin_foil_hat/steward"
 	"github.com/jollheef/tin_foil_hat/vexillary"
@@ -33,6 +33,7 @@ const (
 	greetingMsg         string = "IBST.PSU CTF Flag Receiver\nInput flag: "
 	invalidFlagMsg      string = "Invalid flag\n"
 	alreadyCapturedMsg  string = "Flag already captured\n"
+	capturedByYouMsg    string = "Flag already captured by you\n"
 	capturedMsg         string = "Captured!\n"
 	internalErrorMsg    string = "Internal error\n"
 	flagDoesNotExistMsg string = "Flag does not exist\n"
@@ -41,159 +42,132 @@ const (
 	attemptsLimitMsg    string = "Attack attempts limit exceeded\n"
 	flagYoursMsg        string = "Flag belongs to the attacking team\n"
 	serviceNotUpMsg     string = "The attacking team service is not up\n"
+	flagsLimitMsg       string = "Flags per connection limit exceeded\n"
 )
 
-func parseAddr(addr string) (subnetNo int, err error) {
+var multipleCapture = false
 
-	defer func() {
-		if r := recover(); r != nil {
-			err = errors.New("Cannot parse '" + addr + "'")
-		}
-	}()
-	_, err = fmt.Sscanf(strings.Split(addr, ".")[2], "%d", &subnetNo)
-
-	return
+// SetMultipleCapture allow capture of flag by several teams, otherwise only
+// first team can capture flag
+func SetMultipleCapture(enabled bool) {
+	multipleCapture = enabled
 }
 
-func teamByAddr(db *sql.DB, addr string) (team steward.Team, err error) {
-
-	subnetNo, err := parseAddr(addr)
-	if err != nil {
-		return
-	}
-
-	teams, err := steward.GetTeams(db)
-	if err != nil {
-		return
-	}
-
-	for i := 0; i < len(teams); i++ {
+var flagLifetime = 1
 
-		team = teams[i]
-
-		teamSubnetNo, err := parseAddr(team.Subnet)
-		if err != nil {
-			return team, err
-		}
-
-		if teamSubnetNo == subnetNo {
-			return team, err
-		}
-	}
-
-	err = errors.New("team not found")
-
-	return
+// SetFlagLifetime set amount of rounds while flag can be captured, including
+// round of flag
+func SetFlagLifetime(rounds int) {
+	flagLifetime = rounds
 }
 
-func handler(conn net.Conn, db *sql.DB, priv *rsa.PrivateKey,
-	attackFlow chan scoreboard.Attack) {
-
-	addr := conn.RemoteAddr().String()
-
-	defer conn.Close()
-
-	fmt.Fprint(conn, greetingMsg)
-
-	flag, err := bufio.NewReader(conn).ReadString('\n')
-	if err != nil {
-		log.Println("Read error:", err)
-	}
+func flagExpired(flagRound, round int) bool {
+	return flagRound <= round-flagLifetime || flagRound > round
+}
 
-	flag = strings.Trim(flag, "\n")
+func receiveFlag(db *sql.DB, scheme vexillary.Scheme, team steward.Team,
+	flag string, attackFlow chan scoreboard.Attack) string {
 
-	log.Printf("\tGet flag %s from %s", flag, addr)
+	log.Printf("\tGet flag %s from %s", flag, team.Name)
 
-	valid, err := vexillary.ValidFlag(flag, priv.PublicKey)
+	valid, err := scheme.ValidFlag(flag)
 	if err != nil {
 		log.Println("\tValidate flag failed:", err)
 	}
 	if !valid {
-		fmt.Fprint(conn, invalidFlagMsg)
-		return
+		return invalidFlagMsg
 	}
 
-	exist, err := steward.FlagExist(db, flag)
-	if err != nil {
-		log.Println("\tExist flag check failed:", err)
-		fmt.Fprint(conn, internalErrorMsg)
-		return
-	}
-	if !exist {
-		fmt.Fprint(conn, flagDoesNotExistMsg)
-		return
-	}
+	// Flags with embedded info does not need database for lookup
+	embedder, embedded := scheme.(vexillary.Embedder)
 
-	flg, err := steward.GetFlagInfo(db, flag)
-	if err != nil {
-		log.Println("\tGet flag info failed:", err)
-		fmt.Fprint(conn, internalErrorMsg)
-		return
-	}
+	var flg steward.Flag
 
-	captured, err := steward.AlreadyCaptured(db, flg.ID)
-	if err != nil {
-		log.Println("\tAlready captured check failed:", err)
-		fmt.Fprint(conn, internalErrorMsg)
-		return
-	}
-	if captured {
-		fmt.Fprint(conn, alreadyCapturedMsg)
-		return
-	}
+	if embedded {
+		info, err := embedder.ParseFlag(flag)
+		if err != nil {
+			log.Println("\tParse flag failed:", err)
+			return invalidFlagMsg
+		}
 
-	team, err := teamByAddr(db, addr)
-	if err != nil {
-		log.Println("\tGet team by ip failed:", err)
-		fmt.Fprint(conn, invalidTeamMsg)
-		return
+		flg = steward.Flag{ID: -1, Flag: flag, Round: info.Round,
+			TeamID: info.TeamID, ServiceID: info.ServiceID,
+			Place: info.Place}
+	} else {
+		exist, err := steward.FlagExist(db, flag)
+		if err != nil {
+			log.Println("\tExist flag check failed:", err)
+			return internalErrorMsg
+		}
+		if !exist {
+			return flagDoesNotExistMsg
+		}
+
+		flg, err = steward.GetFlagInfo(db, flag)
+		if err != nil {
+			log.Println("\tGet flag info failed:", err)
+			return internalErrorMsg
+		}
 	}
 
 	if flg.TeamID == team.ID {
 		log.Printf("\tTeam %s try to send their flag", team.Name)
-		fmt.Fprint(conn, flagYoursMsg)
-		return
+		return flagYoursMsg
 	}
 
-	round, err := steward.CurrentRound(db)
+	round, err := currentRound(db)
+	if err != nil {
+		log.Println("\tGet current round failed:", err)
+		return internalErrorMsg
+	}
 
-	if round.ID != flg.Round {
-		log.Printf("\t%s try to send flag from past round", team.Name)
-		fmt.Fprint(conn, flagExpiredMsg)
-		return
+	if flagExpired(flg.Round, round.ID) {
+		log.Printf("\t%s try to send expired flag", team.Name)
+		return flagExpiredMsg
 	}
 
 	roundEndTime := round.StartTime.Add(round.Len)
 
 	if time.Now().After(roundEndTime) {
 		log.Printf("\t%s try to send flag from finished round", team.Name)
-		fmt.Fprint(conn, flagExpiredMsg)
-		return
+		return flagExpiredMsg
 	}
 
-	halfStatus := steward.Status{flg.Round, team.ID, flg.ServiceID,
-		steward.StatusUnknown}
+	halfStatus := steward.Status{Round: round.ID, TeamID: team.ID,
+		ServiceID: flg.ServiceID, State: steward.StatusUnknown}
 	state, err := steward.GetState(db, halfStatus)
 
-	if state != steward.StatusUP {
+	if !counter.Available(state) {
 		log.Printf("\t%s service not ok, cannot capture", team.Name)
-		fmt.Fprint(conn, serviceNotUpMsg)
-		return
+		return serviceNotUpMsg
 	}
 
-	err = steward.CaptureFlag(db, flg.ID, team.ID)
+	res, err := steward.TryCaptureFlag(db, flag, team.ID, round.ID,
+		!multipleCapture)
 	if err != nil {
 		log.Println("\tCapture flag failed:", err)
-		fmt.Fprint(conn, internalErrorMsg)
-		return
+		return internalErrorMsg
+	}
+
+	switch res {
+	case steward.FlagNotFound:
+		return flagDoesNotExistMsg
+	case steward.FlagCapturedByTeam:
+		return capturedByYouMsg
+	case steward.FlagCapturedByOther:
+		return alreadyCapturedMsg
 	}
 
+	log.Printf("	%s captured flag of team %d, service %d, place %d",
+		team.Name, flg.TeamID, flg.ServiceID, flg.Place)
+
 	go func() {
 		attack := scoreboard.Attack{
 			Attacker:  team.ID,
 			Victim:    flg.TeamID,
 			Service:   flg.ServiceID,
 			Timestamp: time.Now().Unix(),
+			Place:     flg.Place,
 		}
 
 		select {
@@ -204,12 +178,67 @@ func handler(conn net.Conn, db *sql.DB, priv *rsa.PrivateKey,
 		}
 	}()
 
-	fmt.Fprint(conn, capturedMsg)
+	return capturedMsg
+}
+
+func handler(conn net.Conn, db *sql.DB, scheme vexillary.Scheme,
+	socketTimeout time.Duration, flagsLimit int,
+	attackFlow chan scoreboard.Attack) {
+
+	addr := conn.RemoteAddr().String()
+
+	defer conn.Close()
+
+	fmt.Fprint(conn, greetingMsg)
+
+	team, teamErr := teamByAddr(db, addr)
+	if teamErr != nil {
+		log.Println("\tGet team by ip failed:", teamErr)
+	}
+
+	reader := bufio.NewReader(conn)
+
+	for received := 0; ; received++ {
+
+		flag, err := reader.ReadString('\n')
+		if err != nil && flag == "" {
+			if err != io.EOF {
+				log.Println("Read error:", err)
+			}
+			return
+		}
+
+		flag = strings.Trim(flag, "\r\n")
+
+		if received >= flagsLimit {
+			log.Printf("\tFlags limit exceeded by %s", addr)
+			fmt.Fprint(conn, flagsLimitMsg)
+			return
+		}
+
+		if teamErr != nil {
+			fmt.Fprint(conn, invalidTeamMsg)
+		} else {
+			fmt.Fprint(conn, receiveFlag(db, scheme, team, flag,
+				attackFlow))
+		}
+
+		if err != nil { // last line without newline
+			return
+		}
+
+		err = conn.SetDeadline(time.Now().Add(socketTimeout))
+		if err != nil {
+			log.Println("Set deadline fail:", err)
+			return
+		}
+	}
 }
 
-// FlagReceiver starts flag receiver
-func FlagReceiver(db *sql.DB, priv *rsa.PrivateKey, addr string,
-	timeout, socketTimeout time.Duration,
+// FlagReceiver starts flag receiver, each connection can send up to
+// flagsLimit flags, one per line
+func FlagReceiver(db *sql.DB, scheme vexillary.Scheme, addr string,
+	timeout, socketTimeout time.Duration, flagsLimit int,
 	attackFlow chan scoreboard.Attack) {
 
 	log.Println("Launching receiver at", addr, "...")
@@ -248,7 +277,8 @@ func FlagReceiver(db *sql.DB, priv *rsa.PrivateKey, addr string,
 			continue
 		}
 
-		go handler(conn, db, priv, attackFlow)
+		go handler(conn, db, scheme, socketTimeout, flagsLimit,
+			attackFlow)
 
 		connects[ip] = time.Now()
 	}
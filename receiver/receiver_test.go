@@ -40,6 +40,9 @@ func openDB() (t testDB, err error) {
 
 	err = steward.CleanDatabase(t.db)
 
+	teams.invalidate()
+	rounds.invalidate()
+
 	return
 }
 
@@ -51,23 +54,68 @@ func (t testDB) Close() {
 	t.db.Close()
 }
 
-func TestparseAddr(t *testing.T) {
+func TestParseAddr(t *testing.T) {
+
+	addrs := map[string]string{
+		"127.0.3.1:44259":         "127.0.3.1",
+		"[fd00:0:3::5]:44259":     "fd00:0:3::5",
+		"[fe80::1%eth0]:44259":    "fe80::1",
+		"10.0.3.1":                "10.0.3.1",
+		"[::ffff:10.0.3.1]:44259": "10.0.3.1",
+	}
 
-	for id := 0; id < 255; id++ {
-		addr := fmt.Sprintf("127.0.%d.1:44259", id)
+	for addr, mustBe := range addrs {
 
-		teamID, err := parseAddr(addr)
+		ip, err := parseAddr(addr)
 		if err != nil {
 			log.Fatalln("Parse addr failed:", err)
 		}
 
-		if teamID != id {
-			log.Fatalf("Parsed [%v] instead [%v]", teamID, id)
+		if !ip.Equal(net.ParseIP(mustBe)) {
+			log.Fatalf("Parsed [%v] instead [%v]", ip, mustBe)
 		}
 	}
+
+	_, err := parseAddr("10.0.3:44259")
+	if err == nil {
+		log.Fatalln("Invalid addr parsed")
+	}
 }
 
-func TestteamByAddr(*testing.T) {
+func TestParseSubnets(t *testing.T) {
+
+	nets, err := parseSubnets("10.0.3.0/24, fd00:0:3::/64 10.1.0.3")
+	if err != nil {
+		log.Fatalln("Parse subnets failed:", err)
+	}
+
+	if len(nets) != 3 {
+		log.Fatalln("Parsed", len(nets), "subnets instead 3")
+	}
+
+	for _, ip := range []string{"10.0.3.200", "fd00:0:3::1", "10.1.0.3"} {
+
+		contains := false
+		for _, ipnet := range nets {
+			if ipnet.Contains(net.ParseIP(ip)) {
+				contains = true
+			}
+		}
+
+		if !contains {
+			log.Fatalln("Address", ip, "not in subnets")
+		}
+	}
+
+	for _, invalid := range []string{"", "10.0.3/24", "10.0.3.0/24,foo"} {
+		_, err = parseSubnets(invalid)
+		if err == nil {
+			log.Fatalln("Invalid subnets", invalid, "parsed")
+		}
+	}
+}
+
+func TestTeamByAddr(*testing.T) {
 
 	db, err := openDB()
 	if err != nil {
@@ -90,6 +138,8 @@ func TestteamByAddr(*testing.T) {
 			log.Fatalln("Add team failed:", err)
 		}
 
+		teams.invalidate()
+
 		addr := fmt.Sprintf("127.0.%d.115:3542", i)
 
 		team, err := teamByAddr(db.db, addr)
@@ -102,6 +152,97 @@ func TestteamByAddr(*testing.T) {
 				team.ID, teamID)
 		}
 	}
+
+	// Team with several subnets
+	t := steward.Team{ID: -1, Name: "Team_20",
+		Subnet: "127.0.20.0/24, fd00:0:20::/64", Vulnbox: "127.0.20.3"}
+
+	teamID, err := steward.AddTeam(db.db, t)
+	if err != nil {
+		log.Fatalln("Add team failed:", err)
+	}
+
+	teams.invalidate()
+
+	for _, addr := range []string{"127.0.20.115:3542",
+		"[fd00:0:20::115]:3542"} {
+
+		team, err := teamByAddr(db.db, addr)
+		if err != nil {
+			log.Fatalln("Get team failed:", err)
+		}
+
+		if team.ID != teamID {
+			log.Fatalf("Get team with id [%v] instead [%v]",
+				team.ID, teamID)
+		}
+	}
+
+	_, err = teamByAddr(db.db, "[fd00:0:21::115]:3542")
+	if err == nil {
+		log.Fatalln("Get team for address outside of all subnets")
+	}
+
+	// Misses do not refresh cache until refresh interval
+	t = steward.Team{ID: -1, Name: "Team_21", Subnet: "fd00:0:21::/64",
+		Vulnbox: "fd00:0:21::3"}
+
+	teamID, err = steward.AddTeam(db.db, t)
+	if err != nil {
+		log.Fatalln("Add team failed:", err)
+	}
+
+	_, err = teamByAddr(db.db, "[fd00:0:21::115]:3542")
+	if err == nil {
+		log.Fatalln("Cache refreshed by miss")
+	}
+
+	teams.mutex.Lock()
+	teams.updated = teams.updated.Add(-teamRefreshInterval)
+	teams.mutex.Unlock()
+
+	team, err := teamByAddr(db.db, "[fd00:0:21::115]:3542")
+	if err != nil || team.ID != teamID {
+		log.Fatalln("Cache is not refreshed by miss:", team, err)
+	}
+}
+
+func TestCurrentRound(*testing.T) {
+
+	db, err := openDB()
+	if err != nil {
+		log.Fatalln("Open database failed:", err)
+	}
+
+	defer db.Close()
+
+	first, err := steward.NewRound(db.db, time.Minute)
+	if err != nil {
+		log.Fatalln("New round failed:", err)
+	}
+
+	round, err := currentRound(db.db)
+	if err != nil || round.ID != first {
+		log.Fatalln("Invalid current round:", round, err)
+	}
+
+	second, err := steward.NewRound(db.db, time.Minute)
+	if err != nil {
+		log.Fatalln("New round failed:", err)
+	}
+
+	// Round is cached until ttl
+	round, err = currentRound(db.db)
+	if err != nil || round.ID != first {
+		log.Fatalln("Current round is not cached:", round, err)
+	}
+
+	time.Sleep(roundCacheTTL)
+
+	round, err = currentRound(db.db)
+	if err != nil || round.ID != second {
+		log.Fatalln("Current round is not refreshed:", round, err)
+	}
 }
 
 func testFlag(addr, flag, response string) {
@@ -145,19 +286,25 @@ func TestReceiver(*testing.T) {
 
 	defer db.Close()
 
-	priv, err := vexillary.GenerateKey()
+	secret, err := vexillary.GenerateSecret()
 	if err != nil {
-		log.Fatalln("Generate key failed:", err)
+		log.Fatalln("Generate secret failed:", err)
+	}
+
+	scheme, err := vexillary.NewHMAC(secret, vexillary.DefaultFormat)
+	if err != nil {
+		log.Fatalln("Create flag scheme failed:", err)
 	}
 
 	addr := "127.0.0.1:65000"
 
-	flag, err := vexillary.GenerateFlag(priv)
+	flag, err := scheme.GenerateFlag()
 	if err != nil {
 		log.Fatalln("Generate flag failed:", err)
 	}
 
-	err = steward.AddFlag(db.db, steward.Flag{-1, flag, 1, 8, 1, ""})
+	err = steward.AddFlag(db.db, steward.Flag{ID: -1, Flag: flag, Round: 1,
+		TeamID: 8, ServiceID: 1, Place: 1})
 	if err != nil {
 		log.Fatalln("Add flag failed:", err)
 	}
@@ -167,9 +314,12 @@ func TestReceiver(*testing.T) {
 		log.Fatalln("New round failed:", err)
 	}
 
+	rounds.invalidate()
+
 	attackFlow := make(chan scoreboard.Attack)
 
-	go FlagReceiver(db.db, priv, addr, time.Nanosecond, time.Minute, attackFlow)
+	go FlagReceiver(db.db, scheme, addr, time.Nanosecond, time.Minute, 1,
+		attackFlow)
 
 	time.Sleep(time.Second) // wait for init listener
 
@@ -185,23 +335,25 @@ func TestReceiver(*testing.T) {
 		log.Fatalln("Add team failed:", err)
 	}
 
+	teams.invalidate()
+
 	serviceID := 1
 
 	// Flag must be captured only if service status ok
-	steward.PutStatus(db.db, steward.Status{firstRound, teamID, serviceID,
-		steward.StatusUP})
+	steward.PutStatus(db.db, steward.Status{Round: firstRound, TeamID: teamID,
+		ServiceID: serviceID, State: steward.StatusUP})
 
 	testFlag(addr, flag, capturedMsg)
 
 	// Correct flag must be captured only one
-	testFlag(addr, flag, alreadyCapturedMsg)
+	testFlag(addr, flag, capturedByYouMsg)
 
 	// Incorrect (non-signed or signed on other key) flag must be invalid
-	testFlag(addr, "1e7b642f2282886377d1655af6097dd6101eac5b=",
+	testFlag(addr, "Q3N8ZKX0W1B5MPLT7HCY2AV9RJ4DSE6=",
 		invalidFlagMsg)
 
 	// Correct flag that does not exist in database must not be captured
-	newFlag, err := vexillary.GenerateFlag(priv)
+	newFlag, err := scheme.GenerateFlag()
 	if err != nil {
 		log.Fatalln("Generate flag failed:", err)
 	}
@@ -209,12 +361,13 @@ func TestReceiver(*testing.T) {
 	testFlag(addr, newFlag, flagDoesNotExistMsg)
 
 	// Submitted flag does not belongs to the attacking team
-	flag4, err := vexillary.GenerateFlag(priv)
+	flag4, err := scheme.GenerateFlag()
 	if err != nil {
 		log.Fatalln("Generate flag failed:", err)
 	}
 
-	err = steward.AddFlag(db.db, steward.Flag{-1, flag4, 1, teamID, 1, ""})
+	err = steward.AddFlag(db.db, steward.Flag{ID: -1, Flag: flag4,
+		Round: 1, TeamID: teamID, ServiceID: 1, Place: 1})
 	if err != nil {
 		log.Fatalln("Add flag failed:", err)
 	}
@@ -222,14 +375,15 @@ func TestReceiver(*testing.T) {
 	testFlag(addr, flag4, flagYoursMsg)
 
 	// Correct flag from another round must not be captured
-	flag2, err := vexillary.GenerateFlag(priv)
+	flag2, err := scheme.GenerateFlag()
 	if err != nil {
 		log.Fatalln("Generate flag failed:", err)
 	}
 
 	curRound, err := steward.CurrentRound(db.db)
 
-	err = steward.AddFlag(db.db, steward.Flag{-1, flag2, curRound.ID, 8, 1, ""})
+	err = steward.AddFlag(db.db, steward.Flag{ID: -1, Flag: flag2,
+		Round: curRound.ID, TeamID: 8, ServiceID: 1, Place: 1})
 	if err != nil {
 		log.Fatalln("Add flag failed:", err)
 	}
@@ -239,6 +393,8 @@ func TestReceiver(*testing.T) {
 		log.Fatalln("New round failed:", err)
 	}
 
+	rounds.invalidate()
+
 	testFlag(addr, flag2, flagExpiredMsg)
 
 	// Correct flag from expired round must not be captured
@@ -248,12 +404,15 @@ func TestReceiver(*testing.T) {
 		log.Fatalln("New round failed:", err)
 	}
 
-	flag3, err := vexillary.GenerateFlag(priv)
+	rounds.invalidate()
+
+	flag3, err := scheme.GenerateFlag()
 	if err != nil {
 		log.Fatalln("Generate flag failed:", err)
 	}
 
-	err = steward.AddFlag(db.db, steward.Flag{-1, flag3, roundID, 8, 1, ""})
+	err = steward.AddFlag(db.db, steward.Flag{ID: -1, Flag: flag3,
+		Round: roundID, TeamID: 8, ServiceID: 1, Place: 1})
 	if err != nil {
 		log.Fatalln("Add flag failed:", err)
 	}
@@ -268,30 +427,39 @@ func TestReceiver(*testing.T) {
 		log.Fatalln("New round failed:", err)
 	}
 
-	flag5, err := vexillary.GenerateFlag(priv)
+	rounds.invalidate()
+
+	flag5, err := scheme.GenerateFlag()
 	if err != nil {
 		log.Fatalln("Generate flag failed:", err)
 	}
 
-	err = steward.AddFlag(db.db, steward.Flag{-1, flag5, roundID, 8,
-		serviceID, ""})
+	err = steward.AddFlag(db.db, steward.Flag{ID: -1, Flag: flag5,
+		Round: roundID, TeamID: 8, ServiceID: serviceID, Place: 1})
 	if err != nil {
 		log.Fatalln("Add flag failed:", err)
 	}
 
-	steward.PutStatus(db.db, steward.Status{roundID, teamID, serviceID,
-		steward.StatusDown})
+	steward.PutStatus(db.db, steward.Status{Round: roundID, TeamID: teamID,
+		ServiceID: serviceID, State: steward.StatusDown})
 
 	testFlag(addr, flag5, serviceNotUpMsg)
 
-	steward.PutStatus(db.db, steward.Status{roundID, teamID, serviceID,
-		steward.StatusUP})
+	// Checker error does not block attacking team
+	steward.PutStatus(db.db, steward.Status{Round: roundID, TeamID: teamID,
+		ServiceID: serviceID, State: steward.StatusError})
+
+	testFlag(addr, flag5, capturedMsg)
+
+	steward.PutStatus(db.db, steward.Status{Round: roundID, TeamID: teamID,
+		ServiceID: serviceID, State: steward.StatusUP})
 
 	// If attempts limit exceeded flag must not be captured
 	newAddr := "127.0.0.1:64000"
 
 	// Start new receiver for test timeouts
-	go FlagReceiver(db.db, priv, newAddr, time.Second, time.Minute, attackFlow)
+	go FlagReceiver(db.db, scheme, newAddr, time.Second, time.Minute, 1,
+		attackFlow)
 
 	time.Sleep(time.Second) // wait for init listener
 
@@ -318,3 +486,248 @@ func TestReceiver(*testing.T) {
 			strings.Trim(response, "\n"))
 	}
 }
+
+func TestReceiverEmbedded(*testing.T) {
+
+	db, err := openDB()
+	if err != nil {
+		log.Fatalln("Open database failed:", err)
+	}
+
+	defer db.Close()
+
+	secret, err := vexillary.GenerateSecret()
+	if err != nil {
+		log.Fatalln("Generate secret failed:", err)
+	}
+
+	scheme, err := vexillary.NewEmbedHMAC(secret, vexillary.DefaultFormat)
+	if err != nil {
+		log.Fatalln("Create flag scheme failed:", err)
+	}
+
+	roundID, err := steward.NewRound(db.db, time.Minute)
+	if err != nil {
+		log.Fatalln("New round failed:", err)
+	}
+
+	rounds.invalidate()
+
+	t := steward.Team{ID: -1, Name: "TestTeam", Subnet: "127.0.0.1/24",
+		Vulnbox: "1"}
+
+	teamID, err := steward.AddTeam(db.db, t)
+	if err != nil {
+		log.Fatalln("Add team failed:", err)
+	}
+
+	teams.invalidate()
+
+	serviceID := 1
+
+	steward.PutStatus(db.db, steward.Status{Round: roundID, TeamID: teamID,
+		ServiceID: serviceID, State: steward.StatusUP})
+
+	addr := "127.0.0.1:65010"
+
+	attackFlow := make(chan scoreboard.Attack)
+
+	go FlagReceiver(db.db, scheme, addr, time.Nanosecond, time.Minute, 1,
+		attackFlow)
+
+	time.Sleep(time.Second) // wait for init listener
+
+	// Owner of flag must be known without flag in database
+	own, err := scheme.EmbedFlag(vexillary.Info{Round: roundID,
+		TeamID: teamID, ServiceID: serviceID})
+	if err != nil {
+		log.Fatalln("Generate flag failed:", err)
+	}
+
+	testFlag(addr, own, flagYoursMsg)
+
+	expired, err := scheme.EmbedFlag(vexillary.Info{Round: roundID - 1,
+		TeamID: teamID + 1, ServiceID: serviceID})
+	if err != nil {
+		log.Fatalln("Generate flag failed:", err)
+	}
+
+	testFlag(addr, expired, flagExpiredMsg)
+
+	// Correct flag that does not exist in database must not be captured
+	info := vexillary.Info{Round: roundID, TeamID: teamID + 1,
+		ServiceID: serviceID}
+
+	newFlag, err := scheme.EmbedFlag(info)
+	if err != nil {
+		log.Fatalln("Generate flag failed:", err)
+	}
+
+	testFlag(addr, newFlag, flagDoesNotExistMsg)
+
+	flag, err := scheme.EmbedFlag(info)
+	if err != nil {
+		log.Fatalln("Generate flag failed:", err)
+	}
+
+	err = steward.AddFlag(db.db, steward.Flag{ID: -1, Flag: flag,
+		Round: info.Round, TeamID: info.TeamID,
+		ServiceID: info.ServiceID})
+	if err != nil {
+		log.Fatalln("Add flag failed:", err)
+	}
+
+	testFlag(addr, flag, capturedMsg)
+
+	testFlag(addr, flag, capturedByYouMsg)
+
+	// Flag captured by another team
+	flag2, err := scheme.EmbedFlag(info)
+	if err != nil {
+		log.Fatalln("Generate flag failed:", err)
+	}
+
+	err = steward.AddFlag(db.db, steward.Flag{ID: -1, Flag: flag2,
+		Round: info.Round, TeamID: info.TeamID,
+		ServiceID: info.ServiceID})
+	if err != nil {
+		log.Fatalln("Add flag failed:", err)
+	}
+
+	_, err = steward.TryCaptureFlag(db.db, flag2, teamID+2, roundID, true)
+	if err != nil {
+		log.Fatalln("Capture flag failed:", err)
+	}
+
+	testFlag(addr, flag2, alreadyCapturedMsg)
+
+	// Flags from previous rounds can be captured while alive
+	SetFlagLifetime(2)
+	defer SetFlagLifetime(1)
+
+	for i := 0; i < 2; i++ {
+		roundID, err = steward.NewRound(db.db, time.Minute)
+		if err != nil {
+			log.Fatalln("New round failed:", err)
+		}
+
+		rounds.invalidate()
+	}
+
+	steward.PutStatus(db.db, steward.Status{Round: roundID, TeamID: teamID,
+		ServiceID: serviceID, State: steward.StatusUP})
+
+	prevInfo := vexillary.Info{Round: roundID - 1, TeamID: teamID + 1,
+		ServiceID: serviceID}
+
+	prevFlag, err := scheme.EmbedFlag(prevInfo)
+	if err != nil {
+		log.Fatalln("Generate flag failed:", err)
+	}
+
+	err = steward.AddFlag(db.db, steward.Flag{ID: -1, Flag: prevFlag,
+		Round: prevInfo.Round, TeamID: prevInfo.TeamID,
+		ServiceID: prevInfo.ServiceID})
+	if err != nil {
+		log.Fatalln("Add flag failed:", err)
+	}
+
+	testFlag(addr, prevFlag, capturedMsg)
+
+	expired, err = scheme.EmbedFlag(vexillary.Info{Round: roundID - 2,
+		TeamID: teamID + 1, ServiceID: serviceID})
+	if err != nil {
+		log.Fatalln("Generate flag failed:", err)
+	}
+
+	testFlag(addr, expired, flagExpiredMsg)
+}
+
+func TestReceiverSession(*testing.T) {
+
+	db, err := openDB()
+	if err != nil {
+		log.Fatalln("Open database failed:", err)
+	}
+
+	defer db.Close()
+
+	t := steward.Team{ID: -1, Name: "TestTeam", Subnet: "127.0.0.1/24",
+		Vulnbox: "1"}
+
+	_, err = steward.AddTeam(db.db, t)
+	if err != nil {
+		log.Fatalln("Add team failed:", err)
+	}
+
+	teams.invalidate()
+
+	secret, err := vexillary.GenerateSecret()
+	if err != nil {
+		log.Fatalln("Generate secret failed:", err)
+	}
+
+	scheme, err := vexillary.NewHMAC(secret, vexillary.DefaultFormat)
+	if err != nil {
+		log.Fatalln("Create flag scheme failed:", err)
+	}
+
+	addr := "127.0.0.1:65020"
+
+	flagsLimit := 3
+
+	go FlagReceiver(db.db, scheme, addr, time.Nanosecond, time.Minute,
+		flagsLimit, make(chan scoreboard.Attack))
+
+	time.Sleep(time.Second) // wait for init listener
+
+	conn, err := net.DialTimeout("tcp", addr, time.Second)
+	if err != nil {
+		log.Fatalln("Connect to receiver failed:", err)
+	}
+
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+
+	_, err = reader.ReadString('\n')
+	if err != nil {
+		log.Fatalln("Invalid greeting:", err)
+	}
+
+	// Greeting ends with prompt without newline
+	_, err = reader.Discard(len(greetingMsg) -
+		strings.Index(greetingMsg, "\n") - 1)
+	if err != nil {
+		log.Fatalln("Invalid greeting:", err)
+	}
+
+	for i := 0; i < flagsLimit+1; i++ {
+		fmt.Fprintf(conn, "INVALID%d\n", i)
+	}
+
+	for i := 0; i < flagsLimit+1; i++ {
+
+		response := invalidFlagMsg
+		if i == flagsLimit {
+			response = flagsLimitMsg
+		}
+
+		msg, err := reader.ReadString('\n')
+		if err != nil {
+			log.Fatalln("Invalid response:", err)
+		}
+
+		if msg != response {
+			log.Fatalf("Invalid message [%v] instead [%v]",
+				strings.Trim(msg, "\n"),
+				strings.Trim(response, "\n"))
+		}
+	}
+
+	// Connection must be closed after limit
+	_, err = reader.ReadString('\n')
+	if err == nil {
+		log.Fatalln("Connection is not closed after flags limit")
+	}
+}
@@ -0,0 +1,69 @@
+/**
+ * @file round.go
+ * @author Mikhail Klementyev jollheef<AT>riseup.net
+ * @license GNU AGPLv3
+ * @date October, 2026
+ * @brief cached current round
+ *
+ * Provide current round for flag checks without database lookup for every
+ * flag, round is refreshed after end of cached round or ttl.
+ */
+
+package receiver
+
+import (
+	"database/sql"
+	"sync"
+	"time"
+)
+
+import "github.com/jollheef/tin_foil_hat/steward"
+
+const roundCacheTTL = time.Second
+
+type roundCache struct {
+	mutex   sync.Mutex
+	round   steward.Round
+	updated time.Time
+}
+
+var rounds roundCache
+
+func (c *roundCache) outdated(now time.Time) bool {
+
+	end := c.round.StartTime.Add(c.round.Len)
+
+	return now.After(c.updated.Add(roundCacheTTL)) ||
+		(now.After(end) && c.updated.Before(end))
+}
+
+func (c *roundCache) invalidate() {
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.updated = time.Time{}
+}
+
+// currentRound returns cached current round, database is used only if
+// cache is outdated
+func currentRound(db *sql.DB) (round steward.Round, err error) {
+
+	rounds.mutex.Lock()
+	defer rounds.mutex.Unlock()
+
+	now := time.Now()
+
+	if rounds.outdated(now) {
+		round, err = steward.CurrentRound(db)
+		if err != nil {
+			return
+		}
+
+		rounds.round = round
+		rounds.updated = now
+	}
+
+	round = rounds.round
+	return
+}
@@ -0,0 +1,189 @@
+/**
+ * @file team.go
+ * @author Mikhail Klementyev jollheef<AT>riseup.net
+ * @license GNU AGPLv3
+ * @date October, 2026
+ * @brief identify team by address
+ *
+ * Provide cached lookup of team by remote address, team may have several
+ * IPv4 and IPv6 subnets separated by comma.
+ */
+
+package receiver
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+import "github.com/jollheef/tin_foil_hat/steward"
+
+const (
+	teamCacheTTL = time.Minute
+	// Cache misses refresh cache not more often than that, so connects from
+	// unknown addresses do not query database every time
+	teamRefreshInterval = 5 * time.Second
+)
+
+type teamNets struct {
+	team steward.Team
+	nets []*net.IPNet
+}
+
+type teamCache struct {
+	mutex   sync.Mutex
+	teams   []teamNets
+	updated time.Time
+}
+
+var teams teamCache
+
+func parseSubnets(subnets string) (nets []*net.IPNet, err error) {
+
+	separator := func(c rune) bool {
+		return c == ',' || c == ' ' || c == '\t'
+	}
+
+	for _, subnet := range strings.FieldsFunc(subnets, separator) {
+
+		if !strings.Contains(subnet, "/") { // single host
+			ip := net.ParseIP(subnet)
+			if ip == nil {
+				err = errors.New("Cannot parse '" + subnet + "'")
+				return
+			}
+
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip = ip.To4()
+				bits = 8 * net.IPv4len
+			}
+
+			nets = append(nets,
+				&net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, ipnet, err := net.ParseCIDR(subnet)
+		if err != nil {
+			return nets, err
+		}
+
+		nets = append(nets, ipnet)
+	}
+
+	if len(nets) == 0 {
+		err = errors.New("no subnets in '" + subnets + "'")
+	}
+
+	return
+}
+
+func parseAddr(addr string) (ip net.IP, err error) {
+
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		// address without port
+		host, err = addr, nil
+	}
+
+	// strip zone of link-local IPv6 address
+	if i := strings.LastIndex(host, "%"); i != -1 {
+		host = host[:i]
+	}
+
+	ip = net.ParseIP(host)
+	if ip == nil {
+		err = errors.New("Cannot parse '" + addr + "'")
+	}
+
+	return
+}
+
+func (c *teamCache) update(db *sql.DB) (err error) {
+
+	list, err := steward.GetTeams(db)
+	if err != nil {
+		return
+	}
+
+	c.teams = nil
+
+	for _, team := range list {
+
+		nets, err := parseSubnets(team.Subnet)
+		if err != nil {
+			log.Printf("Invalid subnet of team %s: %s", team.Name, err)
+			continue
+		}
+
+		c.teams = append(c.teams, teamNets{team, nets})
+	}
+
+	c.updated = time.Now()
+
+	return
+}
+
+func (c *teamCache) find(ip net.IP) (team steward.Team, found bool) {
+
+	for _, t := range c.teams {
+		for _, ipnet := range t.nets {
+			if ipnet.Contains(ip) {
+				return t.team, true
+			}
+		}
+	}
+
+	return
+}
+
+func (c *teamCache) invalidate() {
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.teams = nil
+	c.updated = time.Time{}
+}
+
+// Teams are cached, database is used only if cache is outdated or address
+// does not belong to any cached team (e.g. team was added after cache
+// update), but not more often than teamRefreshInterval
+func teamByAddr(db *sql.DB, addr string) (team steward.Team, err error) {
+
+	ip, err := parseAddr(addr)
+	if err != nil {
+		return
+	}
+
+	teams.mutex.Lock()
+	defer teams.mutex.Unlock()
+
+	now := time.Now()
+
+	if now.Before(teams.updated.Add(teamCacheTTL)) {
+		if team, found := teams.find(ip); found {
+			return team, nil
+		}
+	}
+
+	if now.After(teams.updated.Add(teamRefreshInterval)) {
+		err = teams.update(db)
+		if err != nil {
+			return
+		}
+	}
+
+	team, found := teams.find(ip)
+	if !found {
+		err = errors.New("team not found")
+	}
+
+	return
+}
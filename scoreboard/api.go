@@ -24,6 +24,7 @@ type Attack struct {
 	Victim    int
 	Service   int
 	Timestamp int64
+	Place     int
 }
 
 type broadcast struct {
@@ -42,7 +42,7 @@ func TestAttackFlowHandler(*testing.T) {
 
 	go func() {
 		for i := 0; i < 10; i++ {
-			attackFlow <- Attack{i, i * 2, i * 3, int64(i * 4)}
+			attackFlow <- Attack{i, i * 2, i * 3, int64(i * 4), i * 5}
 		}
 	}()
 
@@ -67,7 +67,7 @@ func TestAttackFlowHandler(*testing.T) {
 
 		ok := false
 		for i := 0; i < 10; i++ {
-			attackEtalon := Attack{i, i * 2, i * 3, int64(i * 4)}
+			attackEtalon := Attack{i, i * 2, i * 3, int64(i * 4), i * 5}
 			if attack == attackEtalon {
 				ok = true
 				break
@@ -39,6 +39,20 @@ func collectTeamResult(db *sql.DB, team steward.Team,
 	tr.Attack = rr.AttackScore
 	tr.Defence = rr.DefenceScore
 
+	scores := make(map[int]ServiceScore) // { service_id : score }
+
+	srs, err := steward.GetLastServiceResults(db, team.ID)
+	if err == nil {
+		for _, sr := range srs {
+			scores[sr.ServiceID] = ServiceScore{Attack: sr.AttackScore,
+				Defence: sr.DefenceScore, SLA: sr.SLA}
+		}
+	}
+
+	for _, svc := range services {
+		tr.Services = append(tr.Services, scores[svc.ID])
+	}
+
 	advisory, err := steward.GetAdvisoryScore(db, team.ID)
 	if err != nil {
 		tr.Advisory = 0
@@ -53,7 +67,8 @@ func collectTeamResult(db *sql.DB, team steward.Team,
 	}
 
 	for _, svc := range services {
-		s := steward.Status{round.ID, team.ID, svc.ID, -1}
+		s := steward.Status{Round: round.ID, TeamID: team.ID,
+			ServiceID: svc.ID, State: -1}
 		state, err := steward.GetState(db, s)
 		if err != nil {
 			// Try to get status from previous round
@@ -64,7 +79,13 @@ func collectTeamResult(db *sql.DB, team steward.Team,
 			}
 		}
 
+		reason, err := steward.GetReason(db, s)
+		if err != nil {
+			reason = ""
+		}
+
 		tr.Status = append(tr.Status, state)
+		tr.Reason = append(tr.Reason, reason)
 	}
 
 	return
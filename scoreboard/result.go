@@ -10,7 +10,10 @@
 
 package scoreboard
 
-import "fmt"
+import (
+	"fmt"
+	"html"
+)
 
 import "github.com/jollheef/tin_foil_hat/steward"
 
@@ -28,6 +31,15 @@ type TeamResult struct {
 	Advisory        int
 	AdvisoryPercent float64
 	Status          []steward.ServiceState
+	Reason          []string       // reasons of status, visible to teams
+	Services        []ServiceScore // in order of services of result
+}
+
+// ServiceScore contain points of team for service
+type ServiceScore struct {
+	Attack  float64
+	Defence float64
+	SLA     float64
 }
 
 func td(s string, best bool) string {
@@ -43,7 +55,7 @@ func td(s string, best bool) string {
 func (tr TeamResult) ToHTML(hideScore bool) string {
 
 	var status string
-	for _, s := range tr.Status {
+	for i, s := range tr.Status {
 
 		var label string
 
@@ -59,9 +71,15 @@ func (tr TeamResult) ToHTML(hideScore bool) string {
 			label = "important"
 		}
 
+		var reason string
+		if i < len(tr.Reason) {
+			reason = html.EscapeString(tr.Reason[i])
+		}
+
 		status += fmt.Sprintf(
-			`<td width="10%%"><span class="label label-%s">%s</span></td>`,
-			label, s.String())
+			`<td width="10%%"><span class="label label-%s" `+
+				`title="%s">%s</span></td>`,
+			label, reason, s.String())
 	}
 
 	var scoreBest, attackBest, defenceBest, advisoryBest bool
@@ -10,6 +10,9 @@ package steward
 
 import "database/sql"
 
+const insertCapture = "INSERT INTO captured_flag (flag_id, team_id, round) " +
+	"VALUES ($1, $2, $3)"
+
 func createCapturedFlagTable(db *sql.DB) (err error) {
 
 	_, err = db.Exec(`
@@ -17,44 +20,189 @@ func createCapturedFlagTable(db *sql.DB) (err error) {
 		id	SERIAL PRIMARY KEY,
 		flag_id	INTEGER NOT NULL,
 		team_id	INTEGER NOT NULL,
+		round	INTEGER NOT NULL DEFAULT 0,
 		timestamp	TIMESTAMP with time zone DEFAULT now()
 	)`)
+	if err != nil {
+		return
+	}
+
+	// For databases created before flag lifetime, flag could be captured
+	// only in round of flag
+	_, err = db.Exec(`ALTER TABLE captured_flag
+		ADD COLUMN IF NOT EXISTS round INTEGER NOT NULL DEFAULT 0`)
+	if err != nil {
+		return
+	}
+
+	_, err = db.Exec(`UPDATE captured_flag SET round=flag.round FROM flag
+		WHERE captured_flag.round=0 AND flag.id=captured_flag.flag_id`)
+	if err != nil {
+		return
+	}
+
+	// For databases created before unique constraint, duplicates made by
+	// concurrent captures are removed, first capture is kept
+	_, err = db.Exec(`
+	DELETE FROM captured_flag dup USING captured_flag first
+		WHERE dup.flag_id=first.flag_id AND dup.team_id=first.team_id
+		AND dup.id > first.id`)
+	if err != nil {
+		return
+	}
+
+	_, err = db.Exec(`
+	CREATE UNIQUE INDEX IF NOT EXISTS captured_flag_flag_id_team_id_idx
+		ON captured_flag (flag_id, team_id)`)
+	if err != nil {
+		return
+	}
+
+	// Databases created with inline constraint have same index twice
+	_, err = db.Exec(`ALTER TABLE captured_flag
+		DROP CONSTRAINT IF EXISTS captured_flag_flag_id_team_id_key`)
 
 	return
 }
 
-// CaptureFlag add correct flag to db
+// CaptureFlag add correct flag to db, flag considered as captured in the
+// round it was put (same as captures made before flag lifetime)
 func CaptureFlag(db *sql.DB, flagID, teamID int) (err error) {
 
-	stmt, err := db.Prepare(
-		"INSERT INTO captured_flag (flag_id, team_id) " +
-			"VALUES ($1, $2)")
+	var round int
+	err = db.QueryRow("SELECT round FROM flag WHERE id=$1",
+		flagID).Scan(&round)
+	if err != nil && err != sql.ErrNoRows {
+		return
+	}
+
+	_, err = db.Exec(insertCapture, flagID, teamID, round)
 	if err != nil {
 		return
 	}
 
-	defer stmt.Close()
+	return
+}
+
+// CaptureResult provide type for result of capture attempt
+type CaptureResult int
 
-	_, err = stmt.Exec(flagID, teamID)
+const (
+	// FlagCaptured Flag captured by team
+	FlagCaptured CaptureResult = iota
+	// FlagCapturedByTeam Flag already captured by the same team
+	FlagCapturedByTeam
+	// FlagCapturedByOther Flag already captured by another team
+	FlagCapturedByOther
+	// FlagNotFound Flag does not exist
+	FlagNotFound
+)
+
+func (res CaptureResult) String() string {
+	switch res {
+	case FlagCaptured:
+		return "captured"
+	case FlagCapturedByTeam:
+		return "captured by team"
+	case FlagCapturedByOther:
+		return "captured by other"
+	case FlagNotFound:
+		return "not found"
+	}
+
+	return "undefined"
+}
+
+// TryCaptureFlag atomically add flag to captured by team in round, if flag
+// is not captured by this team yet, and if exclusive, by any other team
+func TryCaptureFlag(db *sql.DB, flag string, teamID, round int,
+	exclusive bool) (res CaptureResult, err error) {
+
+	tx, err := db.Begin()
+	if err != nil {
+		return
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	// Lock flag for serialize concurrent captures
+	var flagID int
+	err = tx.QueryRow("SELECT id FROM flag WHERE flag=$1 FOR UPDATE",
+		flag).Scan(&flagID)
+	if err == sql.ErrNoRows {
+		res = FlagNotFound
+		err = tx.Rollback()
+		return
+	}
 	if err != nil {
 		return
 	}
 
+	rows, err := tx.Query("SELECT team_id FROM captured_flag "+
+		"WHERE flag_id=$1", flagID)
+	if err != nil {
+		return
+	}
+
+	res = FlagCaptured
+
+	for rows.Next() {
+		var capturedBy int
+
+		err = rows.Scan(&capturedBy)
+		if err != nil {
+			rows.Close()
+			return
+		}
+
+		if capturedBy == teamID {
+			res = FlagCapturedByTeam
+		} else if exclusive && res == FlagCaptured {
+			res = FlagCapturedByOther
+		}
+	}
+
+	rows.Close()
+
+	err = rows.Err()
+	if err != nil {
+		return
+	}
+
+	if res != FlagCaptured {
+		err = tx.Rollback()
+		return
+	}
+
+	_, err = tx.Exec(insertCapture, flagID, teamID, round)
+	if err != nil {
+		return
+	}
+
+	err = tx.Commit()
+
 	return
 }
 
-// GetCapturedFlags get all captured flags for team and round
+// GetCapturedFlags get all flags captured by team in round
 func GetCapturedFlags(db *sql.DB, round, teamID int) (flgs []Flag, err error) {
 
-	stmt, err := db.Prepare("SELECT id, flag, team_id, " +
-		"service_id, cred FROM flag WHERE round=$1")
+	stmt, err := db.Prepare("SELECT flag.id, flag.flag, flag.round, " +
+		"flag.team_id, flag.service_id, flag.cred, flag.place, " +
+		"flag.public_id FROM flag " +
+		"JOIN captured_flag ON captured_flag.flag_id=flag.id " +
+		"WHERE captured_flag.round=$1 AND captured_flag.team_id=$2")
 	if err != nil {
 		return
 	}
 
 	defer stmt.Close()
 
-	rows, err := stmt.Query(round)
+	rows, err := stmt.Query(round, teamID)
 	if err != nil {
 		return
 	}
@@ -63,33 +211,60 @@ func GetCapturedFlags(db *sql.DB, round, teamID int) (flgs []Flag, err error) {
 
 	for rows.Next() {
 		var flag Flag
-		flag.Round = round
 
-		err = rows.Scan(&flag.ID, &flag.Flag, &flag.TeamID,
-			&flag.ServiceID, &flag.Cred)
+		err = rows.Scan(&flag.ID, &flag.Flag, &flag.Round,
+			&flag.TeamID, &flag.ServiceID, &flag.Cred, &flag.Place,
+			&flag.PublicID)
 		if err != nil {
 			return
 		}
 
-		nstmt, err := db.Prepare(
-			"SELECT EXISTS(SELECT id FROM captured_flag " +
-				"WHERE flag_id=$1 AND team_id=$2)")
-		if err != nil {
-			return flgs, err
-		}
+		flgs = append(flgs, flag)
+	}
+
+	return
+}
 
-		defer nstmt.Close()
+// Capture is flag captured by team
+type Capture struct {
+	Flag   Flag
+	TeamID int // capturing team
+}
+
+// GetRoundCaptures get all flags captured in round, in order of capture
+func GetRoundCaptures(db Queryer, round int) (captures []Capture,
+	err error) {
+
+	stmt, err := db.Prepare("SELECT flag.id, flag.flag, flag.round, " +
+		"flag.team_id, flag.service_id, flag.cred, flag.place, " +
+		"flag.public_id, captured_flag.team_id FROM flag " +
+		"JOIN captured_flag ON captured_flag.flag_id=flag.id " +
+		"WHERE captured_flag.round=$1 " +
+		"ORDER BY captured_flag.timestamp, captured_flag.id")
+	if err != nil {
+		return
+	}
+
+	defer stmt.Close()
+
+	rows, err := stmt.Query(round)
+	if err != nil {
+		return
+	}
+
+	defer rows.Close()
 
-		var captured bool
+	for rows.Next() {
+		var c Capture
 
-		err = nstmt.QueryRow(flag.ID, teamID).Scan(&captured)
+		err = rows.Scan(&c.Flag.ID, &c.Flag.Flag, &c.Flag.Round,
+			&c.Flag.TeamID, &c.Flag.ServiceID, &c.Flag.Cred,
+			&c.Flag.Place, &c.Flag.PublicID, &c.TeamID)
 		if err != nil {
-			return flgs, err
+			return
 		}
 
-		if captured {
-			flgs = append(flgs, flag)
-		}
+		captures = append(captures, c)
 	}
 
 	return
@@ -113,3 +288,41 @@ func AlreadyCaptured(db *sql.DB, flagID int) (captured bool, err error) {
 
 	return
 }
+
+// FirstCaptureRound returns round in which flag was captured first time
+func FirstCaptureRound(db Queryer, flagID int) (round int, err error) {
+
+	stmt, err := db.Prepare("SELECT MIN(round) FROM captured_flag " +
+		"WHERE flag_id=$1 HAVING COUNT(*) > 0")
+	if err != nil {
+		return
+	}
+
+	defer stmt.Close()
+
+	err = stmt.QueryRow(flagID).Scan(&round)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+// CountCaptures returns amount of teams captured flag up to round
+func CountCaptures(db Queryer, flagID, round int) (count int, err error) {
+
+	stmt, err := db.Prepare("SELECT COUNT(*) FROM captured_flag " +
+		"WHERE flag_id=$1 AND round<=$2")
+	if err != nil {
+		return
+	}
+
+	defer stmt.Close()
+
+	err = stmt.QueryRow(flagID, round).Scan(&count)
+	if err != nil {
+		return
+	}
+
+	return
+}
@@ -27,6 +27,108 @@ func TestCaptureFlag(t *testing.T) {
 	}
 }
 
+func TestTryCaptureFlag(t *testing.T) {
+
+	db, err := openDB()
+
+	defer db.Close()
+
+	flg := steward.Flag{ID: 1, Flag: "f", Round: 1, TeamID: 1,
+		ServiceID: 1, Cred: "1:2"}
+
+	err = steward.AddFlag(db.db, flg)
+	if err != nil {
+		log.Fatalln("Add flag failed:", err)
+	}
+
+	tries := []struct {
+		flag      string
+		teamID    int
+		exclusive bool
+		res       steward.CaptureResult
+	}{
+		{"f", 20, true, steward.FlagCaptured},
+		{"f", 20, true, steward.FlagCapturedByTeam},
+		{"f", 30, true, steward.FlagCapturedByOther},
+		{"b", 20, true, steward.FlagNotFound},
+		// Flag can be captured by several teams if not exclusive
+		{"f", 30, false, steward.FlagCaptured},
+		{"f", 30, false, steward.FlagCapturedByTeam},
+		{"f", 40, false, steward.FlagCaptured},
+	}
+
+	for _, try := range tries {
+
+		res, err := steward.TryCaptureFlag(db.db, try.flag, try.teamID,
+			flg.Round, try.exclusive)
+		if err != nil {
+			log.Fatalln("Capture flag failed:", err)
+		}
+
+		if res != try.res {
+			log.Fatalln("Capture result", res, "instead", try.res)
+		}
+	}
+
+	// Teams 20, 30 and 40 captured flag in round of flag
+	count, err := steward.CountCaptures(db.db, flg.ID, flg.Round)
+	if err != nil || count != 3 {
+		log.Fatalln("Count captures failed:", count, err)
+	}
+
+	count, err = steward.CountCaptures(db.db, flg.ID, flg.Round-1)
+	if err != nil || count != 0 {
+		log.Fatalln("Captures counted before capture:", count, err)
+	}
+
+	// Same team can not capture flag twice even bypassing checks
+	err = steward.CaptureFlag(db.db, flg.ID, 20)
+	if err == nil {
+		log.Fatalln("Flag captured twice by the same team")
+	}
+}
+
+func TestTryCaptureFlagConcurrent(t *testing.T) {
+
+	db, err := openDB()
+
+	defer db.Close()
+
+	flg := steward.Flag{ID: 1, Flag: "f", Round: 1, TeamID: 1,
+		ServiceID: 1, Cred: "1:2"}
+
+	err = steward.AddFlag(db.db, flg)
+	if err != nil {
+		log.Fatalln("Add flag failed:", err)
+	}
+
+	attempts := 20
+
+	results := make(chan steward.CaptureResult, attempts)
+
+	for i := 0; i < attempts; i++ {
+		go func(teamID int) {
+			res, err := steward.TryCaptureFlag(db.db, flg.Flag, teamID,
+				flg.Round, true)
+			if err != nil {
+				log.Fatalln("Capture flag failed:", err)
+			}
+			results <- res
+		}(10 + i%2)
+	}
+
+	captured := 0
+	for i := 0; i < attempts; i++ {
+		if <-results == steward.FlagCaptured {
+			captured++
+		}
+	}
+
+	if captured != 1 {
+		log.Fatalln("Flag captured", captured, "times")
+	}
+}
+
 func TestGetCapturedFlags(t *testing.T) {
 
 	db, err := openDB()
@@ -37,9 +139,9 @@ func TestGetCapturedFlags(t *testing.T) {
 	team_id := 1
 
 	flg1 := steward.Flag{ID: 1, Flag: "f", Round: round, TeamID: team_id,
-		ServiceID: 1, Cred: "1:2"}
+		ServiceID: 1, Cred: "1:2", Place: 1}
 	flg2 := steward.Flag{ID: 2, Flag: "b", Round: round, TeamID: team_id,
-		ServiceID: 1, Cred: "1:2"}
+		ServiceID: 1, Cred: "1:2", Place: 1}
 
 	err = steward.AddFlag(db.db, flg1)
 	if err != nil {
@@ -75,6 +177,47 @@ func TestGetCapturedFlags(t *testing.T) {
 	if flags1[0] != flg1 || flags2[0] != flg2 {
 		log.Fatalln("Getted flags invalid", flags1[0], flg1, flags2[0], flg2)
 	}
+
+	// Captures of round are ordered by time, not by team
+	err = steward.CaptureFlag(db.db, flg1.ID, 10)
+	if err != nil {
+		log.Fatalln("Capture flag failed:", err)
+	}
+
+	captures, err := steward.GetRoundCaptures(db.db, round)
+	if err != nil {
+		log.Fatalln("Get round captures failed:", err)
+	}
+
+	if len(captures) != 3 || captures[0].TeamID != 20 ||
+		captures[1].TeamID != 30 || captures[2].TeamID != 10 ||
+		captures[2].Flag != flg1 {
+		log.Fatalln("Invalid round captures:", captures)
+	}
+
+	// Flag captured in later round belongs to round of capture
+	_, err = steward.TryCaptureFlag(db.db, flg1.Flag, 40, round+1, false)
+	if err != nil {
+		log.Fatalln("Capture flag failed:", err)
+	}
+
+	flags3, err := steward.GetCapturedFlags(db.db, round, 40)
+	if err != nil {
+		log.Fatalln("Get captured flags failed:", err)
+	}
+
+	if len(flags3) != 0 {
+		log.Fatalln("Get flags captured in other round")
+	}
+
+	flags3, err = steward.GetCapturedFlags(db.db, round+1, 40)
+	if err != nil {
+		log.Fatalln("Get captured flags failed:", err)
+	}
+
+	if len(flags3) != 1 || flags3[0] != flg1 {
+		log.Fatalln("Getted flags invalid", flags3, flg1)
+	}
 }
 
 func TestAlreadyCaptured(t *testing.T) {
@@ -108,3 +251,99 @@ func TestAlreadyCaptured(t *testing.T) {
 		log.Fatalln("Not captured flag is captured")
 	}
 }
+
+func TestCapturedFlagMigration(t *testing.T) {
+
+	db, err := openDB()
+	if err != nil {
+		log.Fatalln("Open database failed:", err)
+	}
+
+	defer db.Close()
+
+	// Table created before unique constraint and flag lifetime
+	_, err = db.db.Exec("DROP TABLE captured_flag")
+	if err != nil {
+		log.Fatalln("Drop table failed:", err)
+	}
+
+	_, err = db.db.Exec(`CREATE TABLE captured_flag (
+		id	SERIAL PRIMARY KEY,
+		flag_id	INTEGER NOT NULL,
+		team_id	INTEGER NOT NULL,
+		timestamp	TIMESTAMP with time zone DEFAULT now())`)
+	if err != nil {
+		log.Fatalln("Create legacy table failed:", err)
+	}
+
+	_, err = db.db.Exec("INSERT INTO captured_flag (flag_id, team_id) " +
+		"VALUES (1, 20), (1, 20), (1, 30)")
+	if err != nil {
+		log.Fatalln("Add legacy captures failed:", err)
+	}
+
+	migrated, err := steward.OpenDatabase(db_path)
+	if err != nil {
+		log.Fatalln("Migrate database failed:", err)
+	}
+
+	defer migrated.Close()
+
+	var count int
+	err = migrated.QueryRow("SELECT COUNT(*) FROM captured_flag " +
+		"WHERE flag_id=1 AND team_id=20").Scan(&count)
+	if err != nil || count != 1 {
+		log.Fatalln("Duplicates are not removed:", count, err)
+	}
+
+	err = steward.CaptureFlag(migrated, 1, 30)
+	if err == nil {
+		log.Fatalln("Duplicate capture added")
+	}
+
+	// Legacy captures belong to round of flag
+	flg := steward.Flag{ID: 1, Flag: "f", Round: 3, TeamID: 1,
+		ServiceID: 1, Cred: "1:2"}
+
+	err = steward.AddFlag(migrated, flg)
+	if err != nil {
+		log.Fatalln("Add flag failed:", err)
+	}
+
+	migrated.Close()
+
+	migrated, err = steward.OpenDatabase(db_path)
+	if err != nil {
+		log.Fatalln("Migrate database failed:", err)
+	}
+
+	defer migrated.Close()
+
+	flags, err := steward.GetCapturedFlags(migrated, flg.Round, 30)
+	if err != nil || len(flags) != 1 || flags[0].ID != flg.ID {
+		log.Fatalln("Round of legacy capture is not set:", flags, err)
+	}
+
+	// Table created with inline unique constraint
+	_, err = migrated.Exec("ALTER TABLE captured_flag ADD CONSTRAINT " +
+		"captured_flag_flag_id_team_id_key UNIQUE (flag_id, team_id)")
+	if err != nil {
+		log.Fatalln("Add constraint failed:", err)
+	}
+
+	migrated.Close()
+
+	migrated, err = steward.OpenDatabase(db_path)
+	if err != nil {
+		log.Fatalln("Migrate database failed:", err)
+	}
+
+	defer migrated.Close()
+
+	err = migrated.QueryRow("SELECT COUNT(*) FROM pg_indexes " +
+		"WHERE tablename='captured_flag' " +
+		"AND indexname <> 'captured_flag_pkey'").Scan(&count)
+	if err != nil || count != 1 {
+		log.Fatalln("Invalid amount of unique indexes:", count, err)
+	}
+}
@@ -18,6 +18,8 @@ type Flag struct {
 	TeamID    int
 	ServiceID int
 	Cred      string
+	Place     int    // flag store of service, starts from one
+	PublicID  string // flag id from checker, e.g. user name
 }
 
 func createFlagTable(db *sql.DB) (err error) {
@@ -29,8 +31,24 @@ func createFlagTable(db *sql.DB) (err error) {
 		flag	TEXT NOT NULL UNIQUE,
 		team_id	INTEGER NOT NULL,
 		service_id	INTEGER NOT NULL,
-		cred	TEXT NOT NULL
+		cred	TEXT NOT NULL,
+		place	INTEGER NOT NULL DEFAULT 1,
+		public_id	TEXT NOT NULL DEFAULT ''
 	)`)
+	if err != nil {
+		return
+	}
+
+	// For databases created before flag places
+	_, err = db.Exec(`ALTER TABLE flag
+		ADD COLUMN IF NOT EXISTS place INTEGER NOT NULL DEFAULT 1`)
+	if err != nil {
+		return
+	}
+
+	// For databases created before json checker protocol
+	_, err = db.Exec(`ALTER TABLE flag
+		ADD COLUMN IF NOT EXISTS public_id TEXT NOT NULL DEFAULT ''`)
 
 	return
 }
@@ -39,16 +57,21 @@ func createFlagTable(db *sql.DB) (err error) {
 func AddFlag(db *sql.DB, flg Flag) error {
 
 	stmt, err := db.Prepare("INSERT INTO flag " +
-		"(round, team_id, service_id, flag, cred) " +
-		"VALUES ($1, $2, $3, $4, $5)")
+		"(round, team_id, service_id, flag, cred, place, public_id) " +
+		"VALUES ($1, $2, $3, $4, $5, $6, $7)")
 	if err != nil {
 		return err
 	}
 
 	defer stmt.Close()
 
+	place := flg.Place
+	if place < 1 {
+		place = 1
+	}
+
 	_, err = stmt.Exec(flg.Round, flg.TeamID, flg.ServiceID,
-		flg.Flag, flg.Cred)
+		flg.Flag, flg.Cred, place, flg.PublicID)
 	if err != nil {
 		return err
 	}
@@ -81,8 +104,8 @@ func GetFlagInfo(db *sql.DB, flag string) (flg Flag, err error) {
 	flg.Flag = flag
 
 	stmt, err := db.Prepare(
-		"SELECT id, round, team_id, service_id, cred " +
-			"FROM flag WHERE flag=$1")
+		"SELECT id, round, team_id, service_id, cred, place, " +
+			"public_id FROM flag WHERE flag=$1")
 	if err != nil {
 		return
 	}
@@ -90,7 +113,7 @@ func GetFlagInfo(db *sql.DB, flag string) (flg Flag, err error) {
 	defer stmt.Close()
 
 	err = stmt.QueryRow(flag).Scan(&flg.ID, &flg.Round, &flg.TeamID,
-		&flg.ServiceID, &flg.Cred)
+		&flg.ServiceID, &flg.Cred, &flg.Place, &flg.PublicID)
 	if err != nil {
 		return
 	}
@@ -98,21 +121,66 @@ func GetFlagInfo(db *sql.DB, flag string) (flg Flag, err error) {
 	return
 }
 
-// GetCred returns credentials for check flag in service
-func GetCred(db *sql.DB, round, team, service int) (flag, cred string, err error) {
+// GetCred returns credentials for check flag in service place
+func GetCred(db *sql.DB, round, team, service, place int) (flag, cred string,
+	err error) {
 
 	stmt, err := db.Prepare("SELECT flag, cred FROM flag WHERE round=$1" +
-		" AND team_id=$2 AND service_id=$3")
+		" AND team_id=$2 AND service_id=$3 AND place=$4")
+	if err != nil {
+		return
+	}
+
+	defer stmt.Close()
+
+	err = stmt.QueryRow(round, team, service, place).Scan(&flag, &cred)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+// GetRandomFlags returns up to count random flags of team service from rounds
+// [fromRound, toRound), only flags successfully put are returned
+func GetRandomFlags(db *sql.DB, team, service, fromRound, toRound,
+	count int) (flgs []Flag, err error) {
+
+	stmt, err := db.Prepare("SELECT id, flag, round, team_id, " +
+		"service_id, cred, place, public_id FROM flag " +
+		"WHERE team_id=$1 AND service_id=$2 " +
+		"AND round>=$3 AND round<$4 AND EXISTS(" +
+		"SELECT id FROM status WHERE status.round=flag.round " +
+		"AND status.team_id=flag.team_id " +
+		"AND status.service_id=flag.service_id " +
+		"AND status.place=flag.place AND status.action='put' " +
+		"AND status.state=$5) " +
+		"ORDER BY random() LIMIT $6")
 	if err != nil {
 		return
 	}
 
 	defer stmt.Close()
 
-	err = stmt.QueryRow(round, team, service).Scan(&flag, &cred)
+	rows, err := stmt.Query(team, service, fromRound, toRound, StatusUP,
+		count)
 	if err != nil {
 		return
 	}
 
+	defer rows.Close()
+
+	for rows.Next() {
+		var flg Flag
+
+		err = rows.Scan(&flg.ID, &flg.Flag, &flg.Round, &flg.TeamID,
+			&flg.ServiceID, &flg.Cred, &flg.Place, &flg.PublicID)
+		if err != nil {
+			return
+		}
+
+		flgs = append(flgs, flg)
+	}
+
 	return
 }
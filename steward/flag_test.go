@@ -9,6 +9,7 @@
 package steward_test
 
 import (
+	"fmt"
 	"log"
 	"testing"
 )
@@ -57,7 +58,7 @@ func TestGetFlagInfo(t *testing.T) {
 	defer db.Close()
 
 	flg := steward.Flag{ID: 1, Flag: "asdfasdf", Round: 5345, TeamID: 433,
-		ServiceID: 353, Cred: "1:2"}
+		ServiceID: 353, Cred: "1:2", Place: 2}
 
 	err = steward.AddFlag(db.db, flg)
 
@@ -78,12 +79,12 @@ func TestGetCred(t *testing.T) {
 	defer db.Close()
 
 	flg := steward.Flag{ID: 1, Flag: "asdfasdf", Round: 5345, TeamID: 433,
-		ServiceID: 353, Cred: "1:2"}
+		ServiceID: 353, Cred: "1:2", Place: 2}
 
 	err = steward.AddFlag(db.db, flg)
 
 	flag, cred, err := steward.GetCred(db.db, flg.Round, flg.TeamID,
-		flg.ServiceID)
+		flg.ServiceID, flg.Place)
 	if err != nil {
 		log.Fatalln("Get cred failed:", err)
 	}
@@ -92,3 +93,71 @@ func TestGetCred(t *testing.T) {
 		log.Fatalln("Gotten cred invalid")
 	}
 }
+
+func TestGetRandomFlags(t *testing.T) {
+
+	db, err := openDB()
+	if err != nil {
+		log.Fatalln("Open database failed:", err)
+	}
+
+	defer db.Close()
+
+	team, service := 12, 21
+
+	for round := 1; round <= 5; round++ {
+
+		state := steward.StatusUP
+		if round == 2 {
+			state = steward.StatusDown // flag was not put
+		}
+
+		err = steward.PutStatus(db.db, steward.Status{Round: round,
+			TeamID: team, ServiceID: service, State: state,
+			Action: "put", Place: 1})
+		if err != nil {
+			log.Fatalln("Put status failed:", err)
+		}
+
+		// Failed check does not mean that flag was not put
+		if round == 3 {
+			err = steward.PutStatus(db.db, steward.Status{
+				Round: round, TeamID: team, ServiceID: service,
+				State: steward.StatusMumble, Action: "chk"})
+			if err != nil {
+				log.Fatalln("Put status failed:", err)
+			}
+		}
+
+		err = steward.AddFlag(db.db, steward.Flag{ID: -1,
+			Flag: fmt.Sprint("flag", round), Round: round,
+			TeamID: team, ServiceID: service, Place: 1})
+		if err != nil {
+			log.Fatalln("Add flag failed:", err)
+		}
+	}
+
+	flgs, err := steward.GetRandomFlags(db.db, team, service, 1, 5, 10)
+	if err != nil {
+		log.Fatalln("Get random flags failed:", err)
+	}
+
+	if len(flgs) != 3 {
+		log.Fatalln("Got", len(flgs), "flags instead 3")
+	}
+
+	for _, flg := range flgs {
+		if flg.Round != 1 && flg.Round != 3 && flg.Round != 4 {
+			log.Fatalln("Got flag from round", flg.Round)
+		}
+	}
+
+	flgs, err = steward.GetRandomFlags(db.db, team, service, 1, 5, 1)
+	if err != nil {
+		log.Fatalln("Get random flags failed:", err)
+	}
+
+	if len(flgs) != 1 {
+		log.Fatalln("Got", len(flgs), "flags instead 1")
+	}
+}
@@ -51,6 +51,18 @@ func NewRound(db *sql.DB, len time.Duration) (round int, err error) {
 	return
 }
 
+// GetLastFinishedRound returns last round which is over (next round is
+// started or its time is over), zero if there is no such round
+func GetLastFinishedRound(db Queryer) (round int, err error) {
+
+	err = db.QueryRow("SELECT COALESCE(MAX(id), 0) FROM round " +
+		"WHERE id < (SELECT MAX(id) FROM round) " +
+		"OR start_time + len_seconds * interval '1 second' <= now()").
+		Scan(&round)
+
+	return
+}
+
 // CurrentRound returns current round
 func CurrentRound(db *sql.DB) (round Round, err error) {
 
@@ -40,7 +40,7 @@ func createRoundResultTable(db *sql.DB) (err error) {
 var addRoundResultMutex sync.Mutex // Use as FIFO queue
 
 // AddRoundResult add round result to database
-func AddRoundResult(db *sql.DB, res RoundResult) (id int, err error) {
+func AddRoundResult(db Queryer, res RoundResult) (id int, err error) {
 
 	addRoundResultMutex.Lock()
 
@@ -97,7 +97,7 @@ func GetRoundResult(db *sql.DB, teamID, round int) (res RoundResult, err error)
 }
 
 // GetLastResult get last round result for team
-func GetLastResult(db *sql.DB, teamID int) (res RoundResult, err error) {
+func GetLastResult(db Queryer, teamID int) (res RoundResult, err error) {
 
 	stmt, err := db.Prepare("SELECT id, round, attack_score, defence_score " +
 		"FROM round_result WHERE team_id=$1 " +
@@ -120,3 +120,31 @@ func GetLastResult(db *sql.DB, teamID int) (res RoundResult, err error) {
 	return
 
 }
+
+// GetLastResultRound get last round with results, zero if there is no
+// results
+func GetLastResultRound(db Queryer) (round int, err error) {
+
+	err = db.QueryRow("SELECT COALESCE(MAX(round), 0) " +
+		"FROM round_result").Scan(&round)
+
+	return
+}
+
+// DeleteRoundResults remove results of all rounds, including results of
+// services
+func DeleteRoundResults(db Queryer) (err error) {
+
+	addRoundResultMutex.Lock()
+
+	defer addRoundResultMutex.Unlock()
+
+	_, err = db.Exec("DELETE FROM round_result")
+	if err != nil {
+		return
+	}
+
+	_, err = db.Exec("DELETE FROM service_result")
+
+	return
+}
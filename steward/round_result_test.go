@@ -91,3 +91,44 @@ func TestGetRoundResult(t *testing.T) {
 			defence_sum)
 	}
 }
+
+func TestDeleteRoundResults(t *testing.T) {
+
+	db, err := openDB()
+	if err != nil {
+		log.Fatalln("Open database failed:", err)
+	}
+
+	defer db.Close()
+
+	for round := 1; round <= 3; round++ {
+		_, err = steward.AddRoundResult(db.db, steward.RoundResult{
+			TeamID: 10, Round: round, AttackScore: 1})
+		if err != nil {
+			log.Fatalln("Add round result failed:", err)
+		}
+	}
+
+	last, err := steward.GetLastResultRound(db.db)
+	if err != nil {
+		log.Fatalln("Get last result round failed:", err)
+	}
+
+	if last != 3 {
+		log.Fatalln("Invalid last result round", last)
+	}
+
+	err = steward.DeleteRoundResults(db.db)
+	if err != nil {
+		log.Fatalln("Delete round results failed:", err)
+	}
+
+	last, err = steward.GetLastResultRound(db.db)
+	if err != nil {
+		log.Fatalln("Get last result round failed:", err)
+	}
+
+	if last != 0 {
+		log.Fatalln("Round results is not deleted, last round", last)
+	}
+}
@@ -54,4 +54,21 @@ func TestRoundWork(t *testing.T) {
 				current_round.StartTime)
 		}
 	}
+
+	// Round is finished if next round is started
+	last, err := steward.GetLastFinishedRound(db.db)
+	if err != nil || last != i-2 {
+		log.Fatalln("Invalid last finished round:", last, err)
+	}
+
+	// or if its time is over
+	_, err = steward.NewRound(db.db, 0)
+	if err != nil {
+		log.Fatalln("Start new round fail:", err)
+	}
+
+	last, err = steward.GetLastFinishedRound(db.db)
+	if err != nil || last != i {
+		log.Fatalln("Invalid last finished round:", last, err)
+	}
 }
@@ -8,7 +8,11 @@
 
 package steward
 
-import "database/sql"
+import (
+	"database/sql"
+	"strings"
+	"time"
+)
 
 // Service contains info about service
 type Service struct {
@@ -17,6 +21,44 @@ type Service struct {
 	Port        int
 	CheckerPath string
 	UDP         bool
+	Places      int    // amount of independent flag stores, at least one
+	Checker     string // name of native checker, used instead of path
+
+	// Checker prints json verdict to stdout instead of plain credentials
+	// or flag, exit code is used as state of legacy checkers
+	JSONProtocol bool
+
+	// Max work time of checker actions, zero means default timeout
+	PutTimeout   Timeout
+	GetTimeout   Timeout
+	CheckTimeout Timeout
+
+	// Probe of udp service: payload sent to port and regexp which response
+	// must match, empty payload means that port is not probed
+	UDPProbe         string
+	UDPProbeResponse string
+	UDPProbeTimeout  Timeout
+
+	// Failed checks repeated up to Retries times with doubled backoff
+	// if state in RetryStates (or in DefaultRetryStates if it's empty)
+	Retries      int
+	RetryBackoff Timeout
+	RetryStates  StateSet
+
+	// Max simultaneous checkers of service, zero means global limit
+	Workers int
+}
+
+// Timeout of checker action with toml unmarshalling support
+type Timeout struct {
+	time.Duration
+}
+
+// UnmarshalTOML for Timeout
+func (t *Timeout) UnmarshalTOML(data []byte) (err error) {
+	raw := strings.Replace(string(data), "\"", "", -1)
+	t.Duration, err = time.ParseDuration(raw)
+	return
 }
 
 func createServiceTable(db *sql.DB) (err error) {
@@ -27,8 +69,59 @@ func createServiceTable(db *sql.DB) (err error) {
 		name	TEXT NOT NULL,
 		port	INTEGER NOT NULL,
 		checker_path	TEXT NOT NULL,
-		udp	BOOLEAN NOT NULL
+		udp	BOOLEAN NOT NULL,
+		places	INTEGER NOT NULL DEFAULT 1,
+		checker	TEXT NOT NULL DEFAULT '',
+		put_timeout	BIGINT NOT NULL DEFAULT 0,
+		get_timeout	BIGINT NOT NULL DEFAULT 0,
+		check_timeout	BIGINT NOT NULL DEFAULT 0,
+		udp_probe	TEXT NOT NULL DEFAULT '',
+		udp_probe_response	TEXT NOT NULL DEFAULT '',
+		udp_probe_timeout	BIGINT NOT NULL DEFAULT 0,
+		retries	INTEGER NOT NULL DEFAULT 0,
+		retry_backoff	BIGINT NOT NULL DEFAULT 0,
+		retry_states	INTEGER NOT NULL DEFAULT 0,
+		json_protocol	BOOLEAN NOT NULL DEFAULT FALSE,
+		workers	INTEGER NOT NULL DEFAULT 0
 	)`)
+	if err != nil {
+		return
+	}
+
+	// For databases created before flag places
+	_, err = db.Exec(`ALTER TABLE service
+		ADD COLUMN IF NOT EXISTS places INTEGER NOT NULL DEFAULT 1`)
+	if err != nil {
+		return
+	}
+
+	// For databases created before native checkers
+	_, err = db.Exec(`ALTER TABLE service
+		ADD COLUMN IF NOT EXISTS checker TEXT NOT NULL DEFAULT ''`)
+	if err != nil {
+		return
+	}
+
+	// For databases created before per-service timeouts
+	for _, column := range []string{
+		"put_timeout BIGINT NOT NULL DEFAULT 0",
+		"get_timeout BIGINT NOT NULL DEFAULT 0",
+		"check_timeout BIGINT NOT NULL DEFAULT 0",
+		"udp_probe TEXT NOT NULL DEFAULT ''",
+		"udp_probe_response TEXT NOT NULL DEFAULT ''",
+		"udp_probe_timeout BIGINT NOT NULL DEFAULT 0",
+		"retries INTEGER NOT NULL DEFAULT 0",
+		"retry_backoff BIGINT NOT NULL DEFAULT 0",
+		"retry_states INTEGER NOT NULL DEFAULT 0",
+		"json_protocol BOOLEAN NOT NULL DEFAULT FALSE",
+		"workers INTEGER NOT NULL DEFAULT 0",
+	} {
+		_, err = db.Exec("ALTER TABLE service " +
+			"ADD COLUMN IF NOT EXISTS " + column)
+		if err != nil {
+			return
+		}
+	}
 
 	return
 }
@@ -37,15 +130,31 @@ func createServiceTable(db *sql.DB) (err error) {
 func AddService(db *sql.DB, svc Service) error {
 
 	stmt, err := db.Prepare(
-		"INSERT INTO service (name, port, checker_path, udp) " +
-			"VALUES ($1, $2, $3, $4)")
+		"INSERT INTO service (name, port, checker_path, udp, places, " +
+			"checker, put_timeout, get_timeout, check_timeout, " +
+			"udp_probe, udp_probe_response, udp_probe_timeout, " +
+			"retries, retry_backoff, retry_states, " +
+			"json_protocol, workers) " +
+			"VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, " +
+			"$11, $12, $13, $14, $15, $16, $17)")
 	if err != nil {
 		return err
 	}
 
 	defer stmt.Close()
 
-	_, err = stmt.Exec(svc.Name, svc.Port, svc.CheckerPath, svc.UDP)
+	places := svc.Places
+	if places < 1 {
+		places = 1
+	}
+
+	_, err = stmt.Exec(svc.Name, svc.Port, svc.CheckerPath, svc.UDP,
+		places, svc.Checker, int64(svc.PutTimeout.Duration),
+		int64(svc.GetTimeout.Duration),
+		int64(svc.CheckTimeout.Duration), svc.UDPProbe,
+		svc.UDPProbeResponse, int64(svc.UDPProbeTimeout.Duration),
+		svc.Retries, int64(svc.RetryBackoff.Duration),
+		svc.RetryStates, svc.JSONProtocol, svc.Workers)
 
 	if err != nil {
 		return err
@@ -57,7 +166,10 @@ func AddService(db *sql.DB, svc Service) error {
 // GetServices get all services from database
 func GetServices(db *sql.DB) (services []Service, err error) {
 
-	rows, err := db.Query("SELECT id,name, port, checker_path, udp " +
+	rows, err := db.Query("SELECT id,name, port, checker_path, udp, " +
+		"places, checker, put_timeout, get_timeout, check_timeout, " +
+		"udp_probe, udp_probe_response, udp_probe_timeout, retries, " +
+		"retry_backoff, retry_states, json_protocol, workers " +
 		"FROM service ")
 	if err != nil {
 		return
@@ -67,13 +179,23 @@ func GetServices(db *sql.DB) (services []Service, err error) {
 
 	for rows.Next() {
 		var svc Service
+		var put, get, check, probe, backoff int64
 
 		err = rows.Scan(&svc.ID, &svc.Name, &svc.Port, &svc.CheckerPath,
-			&svc.UDP)
+			&svc.UDP, &svc.Places, &svc.Checker, &put, &get, &check,
+			&svc.UDPProbe, &svc.UDPProbeResponse, &probe,
+			&svc.Retries, &backoff, &svc.RetryStates,
+			&svc.JSONProtocol, &svc.Workers)
 		if err != nil {
 			return
 		}
 
+		svc.PutTimeout.Duration = time.Duration(put)
+		svc.GetTimeout.Duration = time.Duration(get)
+		svc.CheckTimeout.Duration = time.Duration(check)
+		svc.UDPProbeTimeout.Duration = time.Duration(probe)
+		svc.RetryBackoff.Duration = time.Duration(backoff)
+
 		services = append(services, svc)
 	}
 
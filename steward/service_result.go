@@ -0,0 +1,111 @@
+/**
+ * @file service_result.go
+ * @author Mikhail Klementyev jollheef<AT>riseup.net
+ * @license GNU AGPLv3
+ * @date October, 2026
+ * @brief queries for service result table
+ *
+ * Per-service breakdown of round results, accumulated between rounds same
+ * as round results. SLA is stored as sum of counted rounds and returned as
+ * mean.
+ */
+
+package steward
+
+import "database/sql"
+
+// ServiceResult contains points of team gained by service
+type ServiceResult struct {
+	ID           int
+	TeamID       int
+	ServiceID    int
+	Round        int
+	AttackScore  float64
+	DefenceScore float64
+	SLA          float64 // of round on add, mean of counted rounds on get
+}
+
+func createServiceResultTable(db *sql.DB) (err error) {
+
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS "service_result" (
+		id	SERIAL PRIMARY KEY,
+		team_id	INTEGER NOT NULL,
+		service_id	INTEGER NOT NULL,
+		round	INTEGER,
+		attack_score	FLOAT(24),
+		defence_score	FLOAT(24),
+		sla	FLOAT(24),
+		UNIQUE (team_id, service_id, round)
+	);`)
+
+	return
+}
+
+// AddServiceResult add service result to database, points are added to
+// previous result of team for service
+func AddServiceResult(db Queryer, res ServiceResult) (id int, err error) {
+
+	addRoundResultMutex.Lock()
+
+	defer addRoundResultMutex.Unlock()
+
+	var attack, defence, sla float64
+
+	err = db.QueryRow("SELECT attack_score, defence_score, sla "+
+		"FROM service_result WHERE team_id=$1 AND service_id=$2 "+
+		"AND round < $3 ORDER BY round DESC LIMIT 1",
+		res.TeamID, res.ServiceID, res.Round).Scan(&attack, &defence,
+		&sla)
+	if err != nil && err != sql.ErrNoRows {
+		return
+	}
+
+	res.AttackScore += attack
+	res.DefenceScore += defence
+	res.SLA += sla
+
+	err = db.QueryRow("INSERT INTO service_result "+
+		"(team_id, service_id, round, attack_score, defence_score, "+
+		"sla) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id",
+		res.TeamID, res.ServiceID, res.Round, res.AttackScore,
+		res.DefenceScore, res.SLA).Scan(&id)
+
+	return
+}
+
+// GetLastServiceResults get last results of team for all services, SLA is
+// mean of counted rounds
+func GetLastServiceResults(db *sql.DB, teamID int) (results []ServiceResult,
+	err error) {
+
+	rows, err := db.Query("SELECT id, service_id, round, attack_score, "+
+		"defence_score, sla / (SELECT COUNT(*) FROM service_result "+
+		"counted WHERE counted.team_id=$1 "+
+		"AND counted.service_id=service_result.service_id "+
+		"AND counted.round <= service_result.round) "+
+		"FROM service_result WHERE team_id=$1 "+
+		"AND round = (SELECT MAX(round) FROM service_result "+
+		"WHERE team_id=$1)", teamID)
+	if err != nil {
+		return
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		res := ServiceResult{TeamID: teamID}
+
+		err = rows.Scan(&res.ID, &res.ServiceID, &res.Round,
+			&res.AttackScore, &res.DefenceScore, &res.SLA)
+		if err != nil {
+			return
+		}
+
+		results = append(results, res)
+	}
+
+	err = rows.Err()
+
+	return
+}
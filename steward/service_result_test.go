@@ -0,0 +1,66 @@
+/**
+ * @file service_result_test.go
+ * @author Mikhail Klementyev jollheef<AT>riseup.net
+ * @license GNU AGPLv3
+ * @date October, 2026
+ * @brief test work with service result table
+ */
+
+package steward_test
+
+import (
+	"log"
+	"testing"
+)
+
+import "github.com/jollheef/tin_foil_hat/steward"
+
+func TestAddServiceResult(t *testing.T) {
+
+	db, err := openDB()
+	if err != nil {
+		log.Fatalln("Open database failed:", err)
+	}
+
+	defer db.Close()
+
+	for round := 1; round <= 2; round++ {
+		for svc := 1; svc <= 2; svc++ {
+			_, err = steward.AddServiceResult(db.db,
+				steward.ServiceResult{TeamID: 10,
+					ServiceID: svc, Round: round,
+					AttackScore: 1, DefenceScore: 2,
+					SLA: float64(svc) / 2})
+			if err != nil {
+				log.Fatalln("Add service result failed:", err)
+			}
+		}
+	}
+
+	results, err := steward.GetLastServiceResults(db.db, 10)
+	if err != nil {
+		log.Fatalln("Get last service results failed:", err)
+	}
+
+	if len(results) != 2 {
+		log.Fatalln("Invalid amount of results:", len(results))
+	}
+
+	for _, res := range results {
+		if res.Round != 2 || res.AttackScore != 2 ||
+			res.DefenceScore != 4 ||
+			res.SLA != float64(res.ServiceID)/2 {
+			log.Fatalln("Invalid service result:", res)
+		}
+	}
+
+	err = steward.DeleteRoundResults(db.db)
+	if err != nil {
+		log.Fatalln("Delete round results failed:", err)
+	}
+
+	results, err = steward.GetLastServiceResults(db.db, 10)
+	if err != nil || len(results) != 0 {
+		log.Fatalln("Service results is not deleted:", results, err)
+	}
+}
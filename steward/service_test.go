@@ -37,7 +37,7 @@ func TestGetServices(t *testing.T) {
 	defer db.Close()
 
 	svc := steward.Service{ID: -1, Name: "lol", Port: 10,
-		CheckerPath: "/test", UDP: false}
+		CheckerPath: "/test", UDP: false, Places: 2}
 
 	const services_amount int = 5
 
@@ -8,7 +8,12 @@
 
 package steward
 
-import "database/sql"
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
 
 // ServiceState provide type for service status
 type ServiceState int
@@ -49,12 +54,75 @@ func (state ServiceState) String() string {
 	return "undefined"
 }
 
+// ParseState parse state name such as "up" or "mumble"
+func ParseState(name string) (state ServiceState, err error) {
+
+	for state = StatusUP; state <= StatusUnknown; state++ {
+		if state.String() == name {
+			return
+		}
+	}
+
+	err = errors.New("unknown state '" + name + "'")
+	return
+}
+
+// StateSet is set of service states, e.g. ["down", "mumble"] in config
+type StateSet uint
+
+// DefaultRetryStates are transient failures, corrupt is not retried because
+// lost flag is not transient
+var DefaultRetryStates = NewStateSet(StatusMumble, StatusDown, StatusError)
+
+// NewStateSet returns set of states
+func NewStateSet(states ...ServiceState) (set StateSet) {
+	for _, state := range states {
+		set |= 1 << state
+	}
+	return
+}
+
+// Has returns true if state in set
+func (set StateSet) Has(state ServiceState) bool {
+	return set&(1<<state) != 0
+}
+
+// UnmarshalTOML for StateSet
+func (set *StateSet) UnmarshalTOML(data []byte) (err error) {
+
+	*set = 0
+
+	names := strings.Trim(string(data), "[] \t\n")
+	if names == "" {
+		return
+	}
+
+	for _, name := range strings.Split(names, ",") {
+		var state ServiceState
+		state, err = ParseState(strings.Trim(name, "\" \t\n"))
+		if err != nil {
+			return
+		}
+
+		*set |= NewStateSet(state)
+	}
+
+	return
+}
+
 // Status contains info about services status
 type Status struct {
 	Round     int
 	TeamID    int
 	ServiceID int
 	State     ServiceState
+	Reason    string        // visible to team
+	Action    string        // checker action, e.g. put, get or chk
+	Place     int           // place of flag for put
+	Duration  time.Duration // checker work time
+	ExitCode  int           // checker exit code
+	Logs      string        // private checker logs
+	Timestamp time.Time
 }
 
 func createStatusTable(db *sql.DB) (err error) {
@@ -66,8 +134,33 @@ func createStatusTable(db *sql.DB) (err error) {
 		team_id	INTEGER NOT NULL,
 		service_id	INTEGER NOT NULL,
 		state	INTEGER NOT NULL,
+		reason	TEXT NOT NULL DEFAULT '',
+		action	TEXT NOT NULL DEFAULT '',
+		place	INTEGER NOT NULL DEFAULT 0,
+		duration	BIGINT NOT NULL DEFAULT 0,
+		exit_code	INTEGER NOT NULL DEFAULT 0,
+		logs	TEXT NOT NULL DEFAULT '',
 		timestamp	TIMESTAMP with time zone DEFAULT now()
 	)`)
+	if err != nil {
+		return
+	}
+
+	// For databases created before checker reasons and logs
+	for _, column := range []string{
+		"reason TEXT NOT NULL DEFAULT ''",
+		"action TEXT NOT NULL DEFAULT ''",
+		"duration BIGINT NOT NULL DEFAULT 0",
+		"exit_code INTEGER NOT NULL DEFAULT 0",
+		"logs TEXT NOT NULL DEFAULT ''",
+		"place INTEGER NOT NULL DEFAULT 0",
+	} {
+		_, err = db.Exec("ALTER TABLE status " +
+			"ADD COLUMN IF NOT EXISTS " + column)
+		if err != nil {
+			return
+		}
+	}
 
 	return
 }
@@ -76,7 +169,9 @@ func createStatusTable(db *sql.DB) (err error) {
 func PutStatus(db *sql.DB, status Status) (err error) {
 
 	stmt, err := db.Prepare("INSERT INTO status (round, team_id, " +
-		"service_id, state) VALUES ($1, $2, $3, $4)")
+		"service_id, state, reason, action, place, duration, " +
+		"exit_code, logs) " +
+		"VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)")
 	if err != nil {
 		return
 	}
@@ -84,7 +179,8 @@ func PutStatus(db *sql.DB, status Status) (err error) {
 	defer stmt.Close()
 
 	_, err = stmt.Exec(status.Round, status.TeamID, status.ServiceID,
-		status.State)
+		status.State, status.Reason, status.Action, status.Place,
+		int64(status.Duration), status.ExitCode, status.Logs)
 	if err != nil {
 		return
 	}
@@ -93,7 +189,7 @@ func PutStatus(db *sql.DB, status Status) (err error) {
 }
 
 // GetStates get states for services status
-func GetStates(db *sql.DB, halfStatus Status) (states []ServiceState,
+func GetStates(db Queryer, halfStatus Status) (states []ServiceState,
 	err error) {
 
 	stmt, err := db.Prepare(
@@ -149,3 +245,67 @@ func GetState(db *sql.DB, halfStatus Status) (state ServiceState, err error) {
 
 	return
 }
+
+// GetReason get reason of last state for service status
+func GetReason(db *sql.DB, halfStatus Status) (reason string, err error) {
+
+	stmt, err := db.Prepare(
+		"SELECT reason FROM status WHERE round=$1 AND team_id=$2 " +
+			"AND service_id=$3 ORDER BY id DESC LIMIT 1")
+	if err != nil {
+		return
+	}
+
+	defer stmt.Close()
+
+	err = stmt.QueryRow(halfStatus.Round, halfStatus.TeamID,
+		halfStatus.ServiceID).Scan(&reason)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+// GetStatuses get all status records of team in round with checker details,
+// zero service means all services
+func GetStatuses(db *sql.DB, round, teamID, serviceID int) (statuses []Status,
+	err error) {
+
+	stmt, err := db.Prepare("SELECT round, team_id, service_id, state, " +
+		"reason, action, place, duration, exit_code, logs, " +
+		"timestamp FROM status WHERE round=$1 AND team_id=$2 " +
+		"AND ($3=0 OR service_id=$3) ORDER BY id")
+	if err != nil {
+		return
+	}
+
+	defer stmt.Close()
+
+	rows, err := stmt.Query(round, teamID, serviceID)
+	if err != nil {
+		return
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var status Status
+		var duration int64
+
+		err = rows.Scan(&status.Round, &status.TeamID,
+			&status.ServiceID, &status.State, &status.Reason,
+			&status.Action, &status.Place, &duration,
+			&status.ExitCode,
+			&status.Logs, &status.Timestamp)
+		if err != nil {
+			return
+		}
+
+		status.Duration = time.Duration(duration)
+
+		statuses = append(statuses, status)
+	}
+
+	return
+}
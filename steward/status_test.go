@@ -11,6 +11,7 @@ package steward_test
 import (
 	"log"
 	"testing"
+	"time"
 )
 
 import "github.com/jollheef/tin_foil_hat/steward"
@@ -98,3 +99,115 @@ func TestGetServiceCurrentStatus(t *testing.T) {
 	}
 
 }
+
+func TestParseState(t *testing.T) {
+
+	for state := steward.StatusUP; state <= steward.StatusUnknown; state++ {
+		parsed, err := steward.ParseState(state.String())
+		if err != nil || parsed != state {
+			log.Fatalln("Parse state", state, "failed:", parsed, err)
+		}
+	}
+
+	_, err := steward.ParseState("great")
+	if err == nil {
+		log.Fatalln("Invalid state parsed")
+	}
+}
+
+func TestStateSet(t *testing.T) {
+
+	var set steward.StateSet
+
+	err := set.UnmarshalTOML([]byte(`["down", "corrupt"]`))
+	if err != nil {
+		log.Fatalln("Unmarshal state set failed:", err)
+	}
+
+	if set != steward.NewStateSet(steward.StatusDown,
+		steward.StatusCorrupt) || set.Has(steward.StatusUP) {
+		log.Fatalln("Invalid state set:", set)
+	}
+
+	err = set.UnmarshalTOML([]byte(`["great"]`))
+	if err == nil {
+		log.Fatalln("Invalid state set parsed")
+	}
+}
+
+func TestGetReason(t *testing.T) {
+
+	db, err := openDB()
+	if err != nil {
+		log.Fatalln("Open database failed:", err)
+	}
+
+	defer db.Close()
+
+	status := steward.Status{Round: 1, TeamID: 2, ServiceID: 3,
+		State: steward.StatusMumble, Reason: "Cannot register"}
+
+	err = steward.PutStatus(db.db, status)
+	if err != nil {
+		log.Fatalln("Put status failed:", err)
+	}
+
+	reason, err := steward.GetReason(db.db, status)
+	if err != nil {
+		log.Fatalln("Get reason failed:", err)
+	}
+
+	if reason != status.Reason {
+		log.Fatalln("Get reason", reason, "instead", status.Reason)
+	}
+}
+
+func TestGetStatuses(t *testing.T) {
+
+	db, err := openDB()
+	if err != nil {
+		log.Fatalln("Open database failed:", err)
+	}
+
+	defer db.Close()
+
+	status1 := steward.Status{Round: 42, TeamID: 1, ServiceID: 1,
+		State: steward.StatusUP, Action: "put",
+		Duration: time.Second}
+	status2 := steward.Status{Round: 42, TeamID: 1, ServiceID: 2,
+		State: steward.StatusMumble, Reason: "Cannot register",
+		Action: "chk", Duration: time.Millisecond, ExitCode: 2,
+		Logs: "HTTP 500"}
+
+	for _, status := range []steward.Status{status1, status2} {
+		err = steward.PutStatus(db.db, status)
+		if err != nil {
+			log.Fatalln("Put status failed:", err)
+		}
+	}
+
+	statuses, err := steward.GetStatuses(db.db, 42, 1, 0)
+	if err != nil {
+		log.Fatalln("Get statuses failed:", err)
+	}
+
+	if len(statuses) != 2 {
+		log.Fatalln("Get", len(statuses), "statuses instead 2")
+	}
+
+	statuses, err = steward.GetStatuses(db.db, 42, 1, 2)
+	if err != nil {
+		log.Fatalln("Get statuses failed:", err)
+	}
+
+	if len(statuses) != 1 {
+		log.Fatalln("Get", len(statuses), "statuses instead 1")
+	}
+
+	status := statuses[0]
+	status.Timestamp = time.Time{}
+
+	if status != status2 {
+		log.Fatalln("Get status", status, "instead", status2)
+	}
+}
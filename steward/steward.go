@@ -17,6 +17,15 @@ import (
 	_ "github.com/lib/pq"
 )
 
+// Queryer is database or transaction, accepted by queries used in count of
+// results, so results can be recounted in one transaction
+type Queryer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Prepare(query string) (*sql.Stmt, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
 func createSchema(db *sql.DB) error {
 
 	err := createFlagTable(db)
@@ -59,6 +68,16 @@ func createSchema(db *sql.DB) error {
 		return err
 	}
 
+	err = createServiceResultTable(db)
+	if err != nil {
+		return err
+	}
+
+	err = createVoidRoundTable(db)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -82,7 +101,8 @@ func OpenDatabase(path string) (db *sql.DB, err error) {
 func CleanDatabase(db *sql.DB) (err error) {
 
 	tables := []string{"team", "advisory", "captured_flag", "flag",
-		"service", "status", "round", "round_result"}
+		"service", "status", "round", "round_result", "service_result",
+		"void_round"}
 
 	for _, table := range tables {
 
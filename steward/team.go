@@ -18,6 +18,11 @@ type Team struct {
 	Vulnbox   string
 	UseNetbox bool
 	Netbox    string
+	Token     string
+
+	// Ssh credentials for netbox, empty means defaults from config
+	NetboxUser string
+	NetboxKey  string // path to private key
 }
 
 func createTeamTable(db *sql.DB) (err error) {
@@ -29,8 +34,33 @@ func createTeamTable(db *sql.DB) (err error) {
 		subnet		TEXT NOT NULL UNIQUE,
 		vulnbox		TEXT NOT NULL UNIQUE,
 		use_netbox	BOOLEAN NOT NULL,
-                netbox		TEXT NOT NULL
+                netbox		TEXT NOT NULL,
+		token		TEXT NOT NULL DEFAULT '',
+		netbox_user	TEXT NOT NULL DEFAULT '',
+		netbox_key	TEXT NOT NULL DEFAULT ''
 	)`)
+	if err != nil {
+		return
+	}
+
+	// For databases created before api tokens and native ssh transport
+	for _, column := range []string{
+		"token TEXT NOT NULL DEFAULT ''",
+		"netbox_user TEXT NOT NULL DEFAULT ''",
+		"netbox_key TEXT NOT NULL DEFAULT ''",
+	} {
+		_, err = db.Exec("ALTER TABLE team " +
+			"ADD COLUMN IF NOT EXISTS " + column)
+		if err != nil {
+			return
+		}
+	}
+
+	// Token identifies team, but teams without token have empty one
+	_, err = db.Exec(`
+	CREATE UNIQUE INDEX IF NOT EXISTS team_token_idx
+		ON team (token) WHERE token <> ''`)
+
 	return
 }
 
@@ -38,8 +68,8 @@ func createTeamTable(db *sql.DB) (err error) {
 func AddTeam(db *sql.DB, team Team) (id int, err error) {
 
 	stmt, err := db.Prepare("INSERT INTO team (name, subnet, vulnbox, " +
-		"use_netbox, netbox) " +
-		"VALUES ($1, $2, $3, $4, $5) RETURNING id")
+		"use_netbox, netbox, token, netbox_user, netbox_key) " +
+		"VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id")
 	if err != nil {
 		return
 	}
@@ -47,7 +77,8 @@ func AddTeam(db *sql.DB, team Team) (id int, err error) {
 	defer stmt.Close()
 
 	err = stmt.QueryRow(team.Name, team.Subnet, team.Vulnbox,
-		team.UseNetbox, team.Netbox).Scan(&id)
+		team.UseNetbox, team.Netbox, team.Token, team.NetboxUser,
+		team.NetboxKey).Scan(&id)
 	if err != nil {
 		return
 	}
@@ -58,8 +89,8 @@ func AddTeam(db *sql.DB, team Team) (id int, err error) {
 // GetTeams get all teams from database
 func GetTeams(db *sql.DB) (teams []Team, err error) {
 
-	rows, err := db.Query(
-		"SELECT id, name, subnet, vulnbox, use_netbox, netbox FROM team")
+	rows, err := db.Query("SELECT id, name, subnet, vulnbox, use_netbox, " +
+		"netbox, token, netbox_user, netbox_key FROM team")
 	if err != nil {
 		return
 	}
@@ -70,7 +101,8 @@ func GetTeams(db *sql.DB) (teams []Team, err error) {
 		var team Team
 
 		err = rows.Scan(&team.ID, &team.Name, &team.Subnet,
-			&team.Vulnbox, &team.UseNetbox, &team.Netbox)
+			&team.Vulnbox, &team.UseNetbox, &team.Netbox, &team.Token,
+			&team.NetboxUser, &team.NetboxKey)
 		if err != nil {
 			return
 		}
@@ -85,8 +117,8 @@ func GetTeams(db *sql.DB) (teams []Team, err error) {
 func GetTeam(db *sql.DB, teamID int) (team Team, err error) {
 
 	stmt, err := db.Prepare(
-		"SELECT name, subnet, vulnbox, use_netbox, netbox FROM team " +
-			"WHERE id=$1")
+		"SELECT name, subnet, vulnbox, use_netbox, netbox, token, " +
+			"netbox_user, netbox_key FROM team WHERE id=$1")
 	if err != nil {
 		return
 	}
@@ -96,7 +128,37 @@ func GetTeam(db *sql.DB, teamID int) (team Team, err error) {
 	team.ID = teamID
 
 	err = stmt.QueryRow(teamID).Scan(&team.Name, &team.Subnet,
-		&team.Vulnbox, &team.UseNetbox, &team.Netbox)
+		&team.Vulnbox, &team.UseNetbox, &team.Netbox, &team.Token,
+		&team.NetboxUser, &team.NetboxKey)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+// GetTeamByToken get team by api token from database
+func GetTeamByToken(db *sql.DB, token string) (team Team, err error) {
+
+	if token == "" {
+		err = sql.ErrNoRows
+		return
+	}
+
+	stmt, err := db.Prepare(
+		"SELECT id, name, subnet, vulnbox, use_netbox, netbox, " +
+			"netbox_user, netbox_key FROM team WHERE token=$1")
+	if err != nil {
+		return
+	}
+
+	defer stmt.Close()
+
+	team.Token = token
+
+	err = stmt.QueryRow(token).Scan(&team.ID, &team.Name, &team.Subnet,
+		&team.Vulnbox, &team.UseNetbox, &team.Netbox, &team.NetboxUser,
+		&team.NetboxKey)
 	if err != nil {
 		return
 	}
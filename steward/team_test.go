@@ -42,7 +42,8 @@ func TestGetTeams(t *testing.T) {
 		Vulnbox: "pl.hold1", UseNetbox: false, Netbox: "nb.hold1"}
 	team2 := steward.Team{
 		ID: -1, Name: "MyFooTeam", Subnet: "192.168.112/24",
-		Vulnbox: "pl.hold2", UseNetbox: true, Netbox: "nb.hold2"}
+		Vulnbox: "pl.hold2", UseNetbox: true, Netbox: "nb.hold2",
+		NetboxUser: "checker", NetboxKey: "/etc/tinfoilhat/nb.key"}
 
 	team1.ID, _ = steward.AddTeam(db.db, team1)
 	team2.ID, _ = steward.AddTeam(db.db, team2)
@@ -69,7 +70,8 @@ func TestGetTeam(t *testing.T) {
 
 	team1 := steward.Team{
 		ID: -1, Name: "MySuperTeam", Subnet: "192.168.111/24",
-		Vulnbox: "pl.hold1", UseNetbox: false, Netbox: "nb.hold1"}
+		Vulnbox: "pl.hold1", UseNetbox: false, Netbox: "nb.hold1",
+		Token: "secret"}
 
 	team1.ID, _ = steward.AddTeam(db.db, team1)
 
@@ -87,3 +89,60 @@ func TestGetTeam(t *testing.T) {
 		log.Fatalln("Get invalid team broken")
 	}
 }
+
+func TestGetTeamByToken(t *testing.T) {
+
+	db, err := openDB()
+
+	defer db.Close()
+
+	team1 := steward.Team{
+		ID: -1, Name: "MySuperTeam", Subnet: "192.168.111/24",
+		Vulnbox: "pl.hold1", Token: "secret", NetboxUser: "foo",
+		NetboxKey: "/foo.key"}
+	team2 := steward.Team{
+		ID: -1, Name: "MyFooTeam", Subnet: "192.168.112/24",
+		Vulnbox: "pl.hold2"}
+	team3 := steward.Team{
+		ID: -1, Name: "MyBarTeam", Subnet: "192.168.113/24",
+		Vulnbox: "pl.hold3"}
+
+	team1.ID, _ = steward.AddTeam(db.db, team1)
+	team2.ID, _ = steward.AddTeam(db.db, team2)
+
+	// Several teams can be without token
+	_, err = steward.AddTeam(db.db, team3)
+	if err != nil {
+		log.Fatalln("Add team without token failed:", err)
+	}
+
+	// But token must be unique
+	team3.Name, team3.Subnet, team3.Vulnbox = "MyBazTeam",
+		"192.168.114/24", "pl.hold4"
+	team3.Token = team1.Token
+
+	_, err = steward.AddTeam(db.db, team3)
+	if err == nil {
+		log.Fatalln("Team with same token added")
+	}
+
+	_team1, err := steward.GetTeamByToken(db.db, "secret")
+	if err != nil {
+		log.Fatalln("Get team by token failed:", err)
+	}
+
+	if _team1 != team1 {
+		log.Fatalln("Added team broken")
+	}
+
+	// Team without token must not be authenticated by empty token
+	_, err = steward.GetTeamByToken(db.db, "")
+	if err == nil {
+		log.Fatalln("Get team by empty token broken")
+	}
+
+	_, err = steward.GetTeamByToken(db.db, "invalid")
+	if err == nil {
+		log.Fatalln("Get team by invalid token broken")
+	}
+}
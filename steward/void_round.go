@@ -0,0 +1,46 @@
+/**
+ * @file void_round.go
+ * @author Mikhail Klementyev jollheef<AT>riseup.net
+ * @license GNU AGPLv3
+ * @date October, 2026
+ * @brief queries for void round table
+ *
+ * Rounds of services voided after the fact (e.g. broken checker), states of
+ * voided round are not counted in SLA.
+ */
+
+package steward
+
+import "database/sql"
+
+func createVoidRoundTable(db *sql.DB) (err error) {
+
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS "void_round" (
+		id	SERIAL PRIMARY KEY,
+		round	INTEGER NOT NULL,
+		service_id	INTEGER NOT NULL,
+		UNIQUE (round, service_id)
+	);`)
+
+	return
+}
+
+// VoidRound void round of service, results should be recounted after
+func VoidRound(db *sql.DB, round, serviceID int) (err error) {
+
+	_, err = db.Exec("INSERT INTO void_round (round, service_id) "+
+		"VALUES ($1, $2) ON CONFLICT DO NOTHING", round, serviceID)
+
+	return
+}
+
+// IsVoidRound returns true if round of service is voided
+func IsVoidRound(db Queryer, round, serviceID int) (void bool, err error) {
+
+	err = db.QueryRow("SELECT EXISTS (SELECT 1 FROM void_round "+
+		"WHERE round=$1 AND service_id=$2)", round,
+		serviceID).Scan(&void)
+
+	return
+}
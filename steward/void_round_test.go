@@ -0,0 +1,49 @@
+/**
+ * @file void_round_test.go
+ * @author Mikhail Klementyev jollheef<AT>riseup.net
+ * @license GNU AGPLv3
+ * @date October, 2026
+ * @brief test work with void round table
+ */
+
+package steward_test
+
+import (
+	"log"
+	"testing"
+)
+
+import "github.com/jollheef/tin_foil_hat/steward"
+
+func TestVoidRound(t *testing.T) {
+
+	db, err := openDB()
+	if err != nil {
+		log.Fatalln("Open database failed:", err)
+	}
+
+	defer db.Close()
+
+	// Voided twice is not error
+	for i := 0; i < 2; i++ {
+		err = steward.VoidRound(db.db, 2, 1)
+		if err != nil {
+			log.Fatalln("Void round failed:", err)
+		}
+	}
+
+	void, err := steward.IsVoidRound(db.db, 2, 1)
+	if err != nil || !void {
+		log.Fatalln("Round is not voided:", err)
+	}
+
+	void, err = steward.IsVoidRound(db.db, 2, 2)
+	if err != nil || void {
+		log.Fatalln("Round of other service is voided:", err)
+	}
+
+	void, err = steward.IsVoidRound(db.db, 1, 1)
+	if err != nil || void {
+		log.Fatalln("Other round is voided:", err)
+	}
+}
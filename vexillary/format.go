@@ -0,0 +1,167 @@
+/**
+ * @file format.go
+ * @author Mikhail Klementyev jollheef<AT>riseup.net
+ * @license GNU AGPLv3
+ * @date October, 2026
+ * @brief flag format
+ *
+ * Contain parser of flag format such as "TFH{[a-f0-9]{32}}" and functions
+ * for encode bytes to flag and decode it back.
+ */
+
+package vexillary
+
+import (
+	"errors"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DefaultFormat of flag
+const DefaultFormat = "[A-Z0-9]{31}="
+
+var bodyRegexp = regexp.MustCompile(`\[([^\]]+)\]\{([0-9]+)\}`)
+
+// Format of flag: prefix, body of Length symbols from Alphabet and suffix
+type Format struct {
+	Prefix   string
+	Alphabet string
+	Length   int
+	Suffix   string
+}
+
+func expandAlphabet(set string) (alphabet string, err error) {
+
+	for i := 0; i < len(set); i++ {
+		from, to := set[i], set[i]
+		if i+2 < len(set) && set[i+1] == '-' {
+			to = set[i+2]
+			i += 2
+		}
+
+		if from > to {
+			err = errors.New("invalid range in '" + set + "'")
+			return
+		}
+
+		for c := int(from); c <= int(to); c++ {
+			if strings.IndexByte(alphabet, byte(c)) != -1 {
+				err = errors.New("duplicate symbol in '" + set + "'")
+				return
+			}
+			alphabet += string(rune(c))
+		}
+	}
+
+	if len(alphabet) < 2 {
+		err = errors.New("alphabet '" + set + "' too small")
+	}
+
+	return
+}
+
+// ParseFormat parse format such as "TFH{[a-f0-9]{32}}" or "[A-Z0-9]{31}="
+func ParseFormat(format string) (f Format, err error) {
+
+	loc := bodyRegexp.FindStringSubmatchIndex(format)
+	if loc == nil {
+		err = errors.New("no body like [A-Z0-9]{31} in '" + format + "'")
+		return
+	}
+
+	f.Prefix = format[:loc[0]]
+	f.Suffix = format[loc[1]:]
+
+	f.Alphabet, err = expandAlphabet(format[loc[2]:loc[3]])
+	if err != nil {
+		return
+	}
+
+	f.Length, err = strconv.Atoi(format[loc[4]:loc[5]])
+	if err != nil {
+		return
+	}
+
+	if f.Length == 0 {
+		err = errors.New("zero body length in '" + format + "'")
+	}
+
+	return
+}
+
+// String returns format in parsable form
+func (f Format) String() string {
+	return f.Prefix + "[" + f.Alphabet + "]{" + strconv.Itoa(f.Length) +
+		"}" + f.Suffix
+}
+
+// Capacity returns amount of bytes which can be encoded in flag body
+func (f Format) Capacity() (n int) {
+
+	max := new(big.Int).Exp(big.NewInt(int64(len(f.Alphabet))),
+		big.NewInt(int64(f.Length)), nil)
+
+	return (max.BitLen() - 1) / 8
+}
+
+// Encode bytes to flag, len(buf) must be equal to Capacity()
+func (f Format) Encode(buf []byte) string {
+
+	base := big.NewInt(int64(len(f.Alphabet)))
+
+	value := new(big.Int).SetBytes(buf)
+	digit := new(big.Int)
+
+	body := make([]byte, f.Length)
+	for i := f.Length - 1; i >= 0; i-- {
+		value.DivMod(value, base, digit)
+		body[i] = f.Alphabet[digit.Int64()]
+	}
+
+	return f.Prefix + string(body) + f.Suffix
+}
+
+// Decode flag to bytes
+func (f Format) Decode(flag string) (buf []byte, err error) {
+
+	if len(flag) != len(f.Prefix)+f.Length+len(f.Suffix) {
+		err = errors.New("invalid flag length")
+		return
+	}
+
+	if !strings.HasPrefix(flag, f.Prefix) {
+		err = errors.New("invalid flag prefix")
+		return
+	}
+
+	if !strings.HasSuffix(flag, f.Suffix) {
+		err = errors.New("invalid flag suffix")
+		return
+	}
+
+	base := big.NewInt(int64(len(f.Alphabet)))
+
+	value := new(big.Int)
+	for _, c := range []byte(flag[len(f.Prefix) : len(flag)-len(f.Suffix)]) {
+		digit := strings.IndexByte(f.Alphabet, c)
+		if digit == -1 {
+			err = errors.New("invalid symbol in flag")
+			return
+		}
+
+		value.Mul(value, base)
+		value.Add(value, big.NewInt(int64(digit)))
+	}
+
+	if value.BitLen() > f.Capacity()*8 {
+		err = errors.New("flag body out of range")
+		return
+	}
+
+	buf = make([]byte, f.Capacity())
+	value.FillBytes(buf)
+
+	return
+}
@@ -0,0 +1,82 @@
+/**
+ * @file format_test.go
+ * @author Mikhail Klementyev jollheef<AT>riseup.net
+ * @license GNU AGPLv3
+ * @date October, 2026
+ * @brief test flag format
+ */
+
+package vexillary_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"log"
+	"testing"
+)
+
+import "github.com/jollheef/tin_foil_hat/vexillary"
+
+func TestParseFormat(t *testing.T) {
+
+	f, err := vexillary.ParseFormat("TFH{[a-f0-9]{32}}")
+	if err != nil {
+		log.Fatalln("Parse format error:", err)
+	}
+
+	if f.Prefix != "TFH{" || f.Suffix != "}" || f.Length != 32 ||
+		f.Alphabet != "abcdef0123456789" {
+		log.Fatalln("Invalid parsed format:", f)
+	}
+
+	if f.Capacity() != 16 {
+		log.Fatalln("Capacity", f.Capacity(), "instead 16")
+	}
+
+	f, err = vexillary.ParseFormat(vexillary.DefaultFormat)
+	if err != nil {
+		log.Fatalln("Parse format error:", err)
+	}
+
+	if f.Prefix != "" || f.Suffix != "=" || len(f.Alphabet) != 36 {
+		log.Fatalln("Invalid parsed format:", f)
+	}
+
+	for _, invalid := range []string{"TFH{}", "[a]{10}", "[z-a]{10}",
+		"[aa-z]{10}", "[a-z]{0}"} {
+
+		_, err = vexillary.ParseFormat(invalid)
+		if err == nil {
+			log.Fatalln("Invalid format", invalid, "parsed")
+		}
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+
+	f, _ := vexillary.ParseFormat("TFH{[A-Z0-9]{31}}")
+
+	buf := make([]byte, f.Capacity())
+	rand.Read(buf)
+
+	flag := f.Encode(buf)
+
+	decoded, err := f.Decode(flag)
+	if err != nil {
+		log.Fatalln("Decode error:", err)
+	}
+
+	if !bytes.Equal(buf, decoded) {
+		log.Fatalln("Decoded", decoded, "instead", buf)
+	}
+
+	for _, invalid := range []string{flag[1:], "XFH" + flag[3:],
+		flag[:len(flag)-1] + "]", flag[:5] + "a" + flag[6:],
+		"TFH{9999999999999999999999999999999}"} {
+
+		_, err = f.Decode(invalid)
+		if err == nil {
+			log.Fatalln("Invalid flag", invalid, "decoded")
+		}
+	}
+}
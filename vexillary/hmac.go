@@ -0,0 +1,263 @@
+/**
+ * @file hmac.go
+ * @author Mikhail Klementyev jollheef<AT>riseup.net
+ * @license GNU AGPLv3
+ * @date October, 2026
+ * @brief hmac flag scheme
+ *
+ * Contain flag schemes, where flag body is random nonce (optionally with
+ * info about flag owner) and (truncated) HMAC-SHA256 of it.
+ */
+
+package vexillary
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"strings"
+)
+
+const (
+	secretLen = 32 // bytes
+	nonceLen  = 8  // bytes
+	minMACLen = 8  // bytes
+)
+
+// GenerateSecret generate secret key for hmac scheme
+func GenerateSecret() (secret []byte, err error) {
+
+	secret = make([]byte, secretLen)
+
+	_, err = rand.Read(secret)
+
+	return
+}
+
+// LoadSecret read hex-encoded secret key from file, if file does not exist
+// generate new key and write it to file
+func LoadSecret(path string) (secret []byte, err error) {
+
+	if path == "" {
+		err = errNoKeyFile
+		return
+	}
+
+	buf, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		secret, err = GenerateSecret()
+		if err != nil {
+			return
+		}
+
+		err = ioutil.WriteFile(path,
+			[]byte(hex.EncodeToString(secret)+"\n"), 0600)
+		return
+	}
+	if err != nil {
+		return
+	}
+
+	secret, err = hex.DecodeString(strings.TrimSpace(string(buf)))
+	if err != nil {
+		return
+	}
+
+	if len(secret) < secretLen {
+		err = fmt.Errorf("secret key in %s less than %d bytes",
+			path, secretLen)
+	}
+
+	return
+}
+
+// HMAC scheme with flags authenticated by HMAC-SHA256
+type HMAC struct {
+	secret []byte
+	format Format
+}
+
+// NewHMAC create hmac scheme with flags in format such as "TFH{[a-f0-9]{32}}"
+func NewHMAC(secret []byte, format string) (h HMAC, err error) {
+
+	h.secret = secret
+
+	h.format, err = ParseFormat(format)
+	if err != nil {
+		return
+	}
+
+	if h.format.Capacity() < nonceLen+minMACLen {
+		err = fmt.Errorf("format '%s' has %d bytes capacity, need %d",
+			format, h.format.Capacity(), nonceLen+minMACLen)
+	}
+
+	return
+}
+
+// macLen returns length of truncated hmac, the rest of body is nonce
+func (h HMAC) macLen() int {
+	if h.format.Capacity()-nonceLen > sha256.Size {
+		return sha256.Size
+	}
+	return h.format.Capacity() - nonceLen
+}
+
+func (h HMAC) sum(data []byte) []byte {
+	mac := hmac.New(sha256.New, h.secret)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+// GenerateFlag generate authenticated flag
+func (h HMAC) GenerateFlag() (string, error) {
+
+	buf := make([]byte, h.format.Capacity())
+	nonce := buf[:len(buf)-h.macLen()]
+
+	_, err := rand.Read(nonce)
+	if err != nil {
+		return "", err
+	}
+
+	copy(buf[len(nonce):], h.sum(nonce))
+
+	return h.format.Encode(buf), nil
+}
+
+// ValidFlag verify flag
+func (h HMAC) ValidFlag(flag string) (bool, error) {
+
+	buf, err := h.format.Decode(flag)
+	if err != nil {
+		return false, err
+	}
+
+	nonce := buf[:len(buf)-h.macLen()]
+
+	mac := h.sum(nonce)
+
+	if !hmac.Equal(buf[len(nonce):], mac[:h.macLen()]) {
+		return false, errors.New("invalid flag signature")
+	}
+
+	return true, nil
+}
+
+// EmbedHMAC scheme with round, team, service and place embedded to flag
+// before nonce, hmac covers all of them
+type EmbedHMAC struct {
+	HMAC
+}
+
+// Nonce distinguishes only flags with same info, so it is short, but it can
+// collide for flags without info (checker regenerates flag which already
+// exists)
+const (
+	infoLen       = 9 // round (4), team (2), service (2) and place (1 byte)
+	embedNonceLen = 3 // bytes
+)
+
+// NewEmbedHMAC create hmac scheme with info embedded to flag
+func NewEmbedHMAC(secret []byte, format string) (h EmbedHMAC, err error) {
+
+	h.secret = secret
+
+	h.format, err = ParseFormat(format)
+	if err != nil {
+		return
+	}
+
+	need := infoLen + embedNonceLen + minMACLen
+	if h.format.Capacity() < need {
+		err = fmt.Errorf("format '%s' has %d bytes capacity, need %d",
+			format, h.format.Capacity(), need)
+	}
+
+	return
+}
+
+// macLen returns length of truncated hmac, the rest of body is info and
+// nonce
+func (h EmbedHMAC) macLen() int {
+	if h.format.Capacity()-infoLen-embedNonceLen > sha256.Size {
+		return sha256.Size
+	}
+	return h.format.Capacity() - infoLen - embedNonceLen
+}
+
+// EmbedFlag generate authenticated flag with info
+func (h EmbedHMAC) EmbedFlag(info Info) (string, error) {
+
+	if info.Round < 0 || info.Round > math.MaxUint32 ||
+		info.TeamID < 0 || info.TeamID > math.MaxUint16 ||
+		info.ServiceID < 0 || info.ServiceID > math.MaxUint16 ||
+		info.Place < 0 || info.Place > math.MaxUint8 {
+		return "", fmt.Errorf("info %v cannot be embedded", info)
+	}
+
+	buf := make([]byte, h.format.Capacity())
+	data := buf[:len(buf)-h.macLen()]
+
+	binary.BigEndian.PutUint32(data[0:4], uint32(info.Round))
+	binary.BigEndian.PutUint16(data[4:6], uint16(info.TeamID))
+	binary.BigEndian.PutUint16(data[6:8], uint16(info.ServiceID))
+	data[8] = byte(info.Place)
+
+	_, err := rand.Read(data[infoLen:])
+	if err != nil {
+		return "", err
+	}
+
+	copy(buf[len(data):], h.sum(data))
+
+	return h.format.Encode(buf), nil
+}
+
+// GenerateFlag generate authenticated flag with empty info
+func (h EmbedHMAC) GenerateFlag() (string, error) {
+	return h.EmbedFlag(Info{})
+}
+
+// ParseFlag verify flag and returns info embedded to it
+func (h EmbedHMAC) ParseFlag(flag string) (info Info, err error) {
+
+	buf, err := h.format.Decode(flag)
+	if err != nil {
+		return
+	}
+
+	data := buf[:len(buf)-h.macLen()]
+
+	mac := h.sum(data)
+
+	if !hmac.Equal(buf[len(data):], mac[:h.macLen()]) {
+		err = errors.New("invalid flag signature")
+		return
+	}
+
+	info.Round = int(binary.BigEndian.Uint32(data[0:4]))
+	info.TeamID = int(binary.BigEndian.Uint16(data[4:6]))
+	info.ServiceID = int(binary.BigEndian.Uint16(data[6:8]))
+	info.Place = int(data[8])
+
+	return
+}
+
+// ValidFlag verify flag
+func (h EmbedHMAC) ValidFlag(flag string) (bool, error) {
+
+	_, err := h.ParseFlag(flag)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
@@ -0,0 +1,167 @@
+/**
+ * @file hmac_test.go
+ * @author Mikhail Klementyev jollheef<AT>riseup.net
+ * @license GNU AGPLv3
+ * @date October, 2026
+ * @brief test hmac flag scheme
+ */
+
+package vexillary_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+import "github.com/jollheef/tin_foil_hat/vexillary"
+
+func TestLoadSecret(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "vexillary")
+	if err != nil {
+		log.Fatalln("Create temp dir error:", err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "flag.key")
+
+	secret, err := vexillary.LoadSecret(path)
+	if err != nil {
+		log.Fatalln("Load secret error:", err)
+	}
+
+	loaded, err := vexillary.LoadSecret(path)
+	if err != nil {
+		log.Fatalln("Load secret error:", err)
+	}
+
+	if !bytes.Equal(secret, loaded) {
+		log.Fatalln("Secret changed after reload")
+	}
+
+	err = ioutil.WriteFile(path, []byte("abcdef"), 0600)
+	if err != nil {
+		log.Fatalln("Write file error:", err)
+	}
+
+	_, err = vexillary.LoadSecret(path)
+	if err == nil {
+		log.Fatalln("Load too short secret without error")
+	}
+
+	// Empty path (key_file is not set) must be a clear error
+	_, err = vexillary.LoadScheme("hmac", "", "")
+	if err == nil || !strings.Contains(err.Error(), "key_file") {
+		log.Fatalln("Invalid error of empty key path:", err)
+	}
+}
+
+func TestHMAC(t *testing.T) {
+
+	secret, _ := vexillary.GenerateSecret()
+
+	formats := map[string]string{
+		vexillary.DefaultFormat: `^[A-Z0-9]{31}=$`,
+		"TFH{[a-f0-9]{32}}":     `^TFH\{[a-f0-9]{32}\}$`,
+		"[a-z]{128}":            `^[a-z]{128}$`,
+	}
+
+	for format, pattern := range formats {
+
+		scheme, err := vexillary.NewHMAC(secret, format)
+		if err != nil {
+			log.Fatalln("Create scheme error:", err)
+		}
+
+		flag, err := scheme.GenerateFlag()
+		if err != nil {
+			log.Fatalln("Generate flag error:", err)
+		}
+
+		if !regexp.MustCompile(pattern).MatchString(flag) {
+			log.Fatalln("Flag", flag, "does not match", pattern)
+		}
+
+		valid, err := scheme.ValidFlag(flag)
+		if !valid {
+			log.Fatalln("Valid flag is invalid:", err)
+		}
+
+		// Flag signed by another key must be invalid
+		otherSecret, _ := vexillary.GenerateSecret()
+		other, _ := vexillary.NewHMAC(otherSecret, format)
+
+		otherFlag, _ := other.GenerateFlag()
+
+		valid, err = scheme.ValidFlag(otherFlag)
+		if valid {
+			log.Fatalln("Foreign flag is valid:", err)
+		}
+	}
+
+	// Format without enough space for hmac must be rejected
+	_, err := vexillary.NewHMAC(secret, "[a-f0-9]{16}")
+	if err == nil {
+		log.Fatalln("Too short format accepted")
+	}
+}
+
+func TestEmbedHMAC(t *testing.T) {
+
+	secret, _ := vexillary.GenerateSecret()
+
+	scheme, err := vexillary.NewEmbedHMAC(secret, vexillary.DefaultFormat)
+	if err != nil {
+		log.Fatalln("Create scheme error:", err)
+	}
+
+	info := vexillary.Info{Round: 100500, TeamID: 42, ServiceID: 7,
+		Place: 3}
+
+	flag, err := vexillary.NewFlag(scheme, info)
+	if err != nil {
+		log.Fatalln("Generate flag error:", err)
+	}
+
+	valid, err := scheme.ValidFlag(flag)
+	if !valid {
+		log.Fatalln("Valid flag is invalid:", err)
+	}
+
+	parsed, err := scheme.ParseFlag(flag)
+	if err != nil {
+		log.Fatalln("Parse flag error:", err)
+	}
+
+	if parsed != info {
+		log.Fatalln("Parsed", parsed, "instead", info)
+	}
+
+	// Flag from plain hmac scheme with the same key must be invalid
+	plain, _ := vexillary.NewHMAC(secret, vexillary.DefaultFormat)
+
+	plainFlag, _ := plain.GenerateFlag()
+
+	_, err = scheme.ParseFlag(plainFlag)
+	if err == nil {
+		log.Fatalln("Flag from other scheme parsed")
+	}
+
+	_, err = scheme.EmbedFlag(vexillary.Info{Round: 1, TeamID: 1 << 16,
+		ServiceID: 1})
+	if err == nil {
+		log.Fatalln("Too big team id embedded")
+	}
+
+	_, err = vexillary.NewEmbedHMAC(secret, "TFH{[a-f0-9]{32}}")
+	if err == nil {
+		log.Fatalln("Too short format accepted")
+	}
+}
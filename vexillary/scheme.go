@@ -0,0 +1,105 @@
+/**
+ * @file scheme.go
+ * @author Mikhail Klementyev jollheef<AT>riseup.net
+ * @license GNU AGPLv3
+ * @date October, 2026
+ * @brief flag schemes
+ *
+ * Contain interfaces for generate and validate flags and legacy rsa scheme.
+ */
+
+package vexillary
+
+import (
+	"crypto/rsa"
+	"errors"
+)
+
+// Scheme generate and validate flags
+type Scheme interface {
+	GenerateFlag() (string, error)
+	ValidFlag(flag string) (bool, error)
+}
+
+// Info about flag owner
+type Info struct {
+	Round     int
+	TeamID    int
+	ServiceID int
+	Place     int
+}
+
+// Embedder is a scheme which embed info about flag owner to flag, so it can
+// be parsed back without database
+type Embedder interface {
+	Scheme
+	EmbedFlag(info Info) (string, error)
+	ParseFlag(flag string) (Info, error)
+}
+
+// NewFlag generate flag, info is embedded to flag if scheme supports it
+func NewFlag(scheme Scheme, info Info) (string, error) {
+
+	if embedder, ok := scheme.(Embedder); ok {
+		return embedder.EmbedFlag(info)
+	}
+
+	return scheme.GenerateFlag()
+}
+
+// RSA scheme with flags signed by rsa key (legacy 41 characters format)
+type RSA struct {
+	priv *rsa.PrivateKey
+}
+
+// NewRSA create rsa scheme
+func NewRSA(priv *rsa.PrivateKey) RSA {
+	return RSA{priv: priv}
+}
+
+// GenerateFlag generate signed flag
+func (r RSA) GenerateFlag() (string, error) {
+	return GenerateFlag(r.priv)
+}
+
+// ValidFlag verify flag
+func (r RSA) ValidFlag(flag string) (bool, error) {
+	return ValidFlag(flag, r.priv.PublicKey)
+}
+
+// LoadScheme create scheme by name ("hmac", "hmac-embed" or "rsa") with key
+// from file, format used only by hmac schemes
+func LoadScheme(name, keyFile, format string) (Scheme, error) {
+
+	if format == "" {
+		format = DefaultFormat
+	}
+
+	switch name {
+	case "", "hmac":
+		secret, err := LoadSecret(keyFile)
+		if err != nil {
+			return nil, err
+		}
+
+		return NewHMAC(secret, format)
+
+	case "hmac-embed":
+		secret, err := LoadSecret(keyFile)
+		if err != nil {
+			return nil, err
+		}
+
+		return NewEmbedHMAC(secret, format)
+
+	case "rsa":
+		priv, err := LoadKey(keyFile)
+		if err != nil {
+			return nil, err
+		}
+
+		return NewRSA(priv), nil
+	}
+
+	return nil, errors.New("unknown flag scheme '" + name + "'")
+}
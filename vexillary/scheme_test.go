@@ -0,0 +1,60 @@
+/**
+ * @file scheme_test.go
+ * @author Mikhail Klementyev jollheef<AT>riseup.net
+ * @license GNU AGPLv3
+ * @date October, 2026
+ * @brief test flag schemes
+ */
+
+package vexillary_test
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+import "github.com/jollheef/tin_foil_hat/vexillary"
+
+func TestLoadScheme(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "vexillary")
+	if err != nil {
+		log.Fatalln("Create temp dir error:", err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	for _, name := range []string{"hmac", "hmac-embed", "rsa"} {
+
+		path := filepath.Join(dir, name+".key")
+
+		scheme, err := vexillary.LoadScheme(name, path, "")
+		if err != nil {
+			log.Fatalln("Load scheme error:", err)
+		}
+
+		flag, err := scheme.GenerateFlag()
+		if err != nil {
+			log.Fatalln("Generate flag error:", err)
+		}
+
+		// Flags must be valid after restart
+		scheme, err = vexillary.LoadScheme(name, path, "")
+		if err != nil {
+			log.Fatalln("Load scheme error:", err)
+		}
+
+		valid, err := scheme.ValidFlag(flag)
+		if !valid {
+			log.Fatalln("Valid flag is invalid:", err)
+		}
+	}
+
+	_, err = vexillary.LoadScheme("unknown", filepath.Join(dir, "key"), "")
+	if err == nil {
+		log.Fatalln("Unknown scheme loaded")
+	}
+}
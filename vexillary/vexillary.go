@@ -6,7 +6,7 @@
  * @brief work with flags
  *
  * Contain functions for work with flags, such as generate rsa key for validate
- * flag, generate flag and validate flag.
+ * flag, load it from file, generate flag and validate flag.
  */
 
 package vexillary
@@ -14,17 +14,68 @@ package vexillary
 import (
 	"crypto/rand"
 	"crypto/rsa"
+	"crypto/x509"
 	"encoding/hex"
+	"encoding/pem"
 	"errors"
 	"fmt"
+	"io/ioutil"
+	"os"
 )
 
+// errNoKeyFile returned instead of attempt to create key file with empty
+// path (e.g. config without [Vexillary] section)
+var errNoKeyFile = errors.New("vexillary: key_file must be set")
+
 // GenerateKey generate rsa key
 func GenerateKey() (priv *rsa.PrivateKey, err error) {
 	// 128 is minimal valid length for RSA key, which can validate flag
 	return rsa.GenerateKey(rand.Reader, 128)
 }
 
+// LoadKey read rsa key from file, if file does not exist generate new key
+// and write it to file
+func LoadKey(path string) (priv *rsa.PrivateKey, err error) {
+
+	if path == "" {
+		err = errNoKeyFile
+		return
+	}
+
+	buf, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		priv, err = GenerateKey()
+		if err != nil {
+			return
+		}
+
+		err = SaveKey(path, priv)
+		return
+	}
+	if err != nil {
+		return
+	}
+
+	block, _ := pem.Decode(buf)
+	if block == nil {
+		err = errors.New("no pem data in " + path)
+		return
+	}
+
+	return x509.ParsePKCS1PrivateKey(block.Bytes)
+}
+
+// SaveKey write rsa key to file
+func SaveKey(path string, priv *rsa.PrivateKey) error {
+
+	block := &pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(priv),
+	}
+
+	return ioutil.WriteFile(path, pem.EncodeToMemory(block), 0600)
+}
+
 // GenerateFlag generate signed flag
 func GenerateFlag(priv *rsa.PrivateKey) (string, error) {
 
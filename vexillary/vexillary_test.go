@@ -9,7 +9,11 @@
 package vexillary_test
 
 import (
+	"io/ioutil"
 	"log"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -23,6 +27,54 @@ func TestGenerateKey(t *testing.T) {
 	}
 }
 
+func TestLoadKey(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "vexillary")
+	if err != nil {
+		log.Fatalln("Create temp dir error:", err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "flag.key")
+
+	// Key must be generated if file does not exist
+	priv, err := vexillary.LoadKey(path)
+	if err != nil {
+		log.Fatalln("Load key error:", err)
+	}
+
+	flag, _ := vexillary.GenerateFlag(priv)
+
+	// Key must be same after reload
+	loaded, err := vexillary.LoadKey(path)
+	if err != nil {
+		log.Fatalln("Load key error:", err)
+	}
+
+	valid, err := vexillary.ValidFlag(flag, loaded.PublicKey)
+	if !valid {
+		log.Fatalln("Flag is invalid after reload key:", err)
+	}
+
+	// Garbage in key file must be an error
+	err = ioutil.WriteFile(path, []byte("garbage"), 0600)
+	if err != nil {
+		log.Fatalln("Write file error:", err)
+	}
+
+	_, err = vexillary.LoadKey(path)
+	if err == nil {
+		log.Fatalln("Load key from invalid file without error")
+	}
+
+	// Empty path (key_file is not set) must be a clear error
+	_, err = vexillary.LoadKey("")
+	if err == nil || !strings.Contains(err.Error(), "key_file") {
+		log.Fatalln("Invalid error of empty key path:", err)
+	}
+}
+
 func TestGenerateFlag(t *testing.T) {
 
 	priv, _ := vexillary.GenerateKey()